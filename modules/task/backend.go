@@ -0,0 +1,92 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package task
+
+import (
+	"context"
+	"time"
+)
+
+// Task is a unit of work enqueued on a Backend. Name identifies the handler the worker side
+// should dispatch to; Payload carries its arguments plus anything EnqueueContext stashed for
+// ContextWithLogFields to recover on the worker side.
+type Task struct {
+	Name    string
+	Payload map[string]interface{}
+}
+
+// ScheduleID identifies a recurring job registered via Backend.Schedule, for later reference
+// (e.g. cancellation, once that's needed) by the caller that created it.
+type ScheduleID string
+
+// Backend is the seam between the task module and the queueing system actually moving tasks
+// from an enqueuing caller to a worker. Implementations live under modules/task/backend/*,
+// selected via the `task.backend` config key, and are registered by name with RegisterBackend so
+// multiple backends (e.g. a default queue plus a low-priority one) can coexist in one host.
+type Backend interface {
+	// Enqueue hands t to the backend for eventual delivery to a worker. It should return
+	// promptly; callers needing delay or scheduling use EnqueueIn/EnqueueAt instead.
+	Enqueue(ctx context.Context, t Task) error
+
+	// EnqueueIn is Enqueue, except t only becomes visible to a worker after delay.
+	EnqueueIn(ctx context.Context, t Task, delay time.Duration) error
+
+	// EnqueueAt is Enqueue, except t only becomes visible to a worker at fireTime.
+	EnqueueAt(ctx context.Context, t Task, fireTime time.Time) error
+
+	// Schedule registers t to be enqueued repeatedly according to spec, a standard 5-field
+	// cron expression (minute hour day-of-month month day-of-week). Calling Schedule again
+	// with the same spec and t.Name is idempotent: it returns the existing ScheduleID rather
+	// than registering a second copy of the job.
+	Schedule(spec string, t Task) (ScheduleID, error)
+
+	// Start begins consuming tasks, dispatching each to its registered handler. It should
+	// not block; workers run on goroutines owned by the Backend.
+	Start() error
+
+	// Stop drains in-flight tasks and releases the backend's resources. It should block
+	// until outstanding work has either completed or been returned to the queue for
+	// another worker to pick up.
+	Stop() error
+}
+
+// NopBackend is a Backend that accepts and immediately discards every task. It is the
+// default backend for hosts that haven't configured one, and is useful in tests that don't
+// care about task delivery.
+type NopBackend struct{}
+
+// Enqueue implements Backend by discarding t.
+func (NopBackend) Enqueue(ctx context.Context, t Task) error { return nil }
+
+// EnqueueIn implements Backend by discarding t.
+func (NopBackend) EnqueueIn(ctx context.Context, t Task, delay time.Duration) error { return nil }
+
+// EnqueueAt implements Backend by discarding t.
+func (NopBackend) EnqueueAt(ctx context.Context, t Task, fireTime time.Time) error { return nil }
+
+// Schedule implements Backend by never actually firing t.
+func (NopBackend) Schedule(spec string, t Task) (ScheduleID, error) { return "", nil }
+
+// Start implements Backend as a no-op.
+func (NopBackend) Start() error { return nil }
+
+// Stop implements Backend as a no-op.
+func (NopBackend) Stop() error { return nil }
@@ -0,0 +1,311 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package amqp implements a task.Backend on top of an AMQP broker (e.g. RabbitMQ). Retries
+// are driven by the broker itself: a rejected delivery is republished to a per-attempt retry
+// queue whose `x-message-ttl` acts as the backoff delay, and whose `x-dead-letter-exchange`
+// routes expired messages back to the work queue; a delivery that exhausts its retry queues
+// is published to the dead-letter queue instead of being requeued again.
+package amqp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/fx/modules/task"
+
+	"github.com/streadway/amqp"
+	"github.com/uber-go/tally"
+)
+
+// Configuration configures the AMQP task backend, typically loaded from a service's YAML
+// config under the `task.amqp` key.
+type Configuration struct {
+	URL         string      `yaml:"url"`
+	Queue       string      `yaml:"queue"`
+	Concurrency int         `yaml:"concurrency"`
+	MaxAttempts int         `yaml:"maxAttempts"`
+	Retry       RetryConfig `yaml:"retry"`
+}
+
+// RetryConfig controls the exponential backoff applied between delivery attempts, implemented
+// as the TTL on each attempt's retry queue.
+type RetryConfig struct {
+	BaseDelay time.Duration `yaml:"baseDelay"`
+	MaxDelay  time.Duration `yaml:"maxDelay"`
+}
+
+// HandlerFunc processes a single task's payload. An error (or panic, which is recovered and
+// treated as an error) causes the delivery to be rejected and routed for retry.
+type HandlerFunc func(ctx context.Context, payload map[string]interface{}) error
+
+type envelope struct {
+	Name     string                 `json:"name"`
+	Payload  map[string]interface{} `json:"payload"`
+	Attempts int                    `json:"attempts"`
+}
+
+// Backend is a task.Backend backed by an AMQP broker. Construct it with New and register at
+// least one handler with HandleFunc before Start.
+//
+// AMQP has no native way to delay or recur a publish, so EnqueueIn, EnqueueAt, and Schedule
+// are provided by an embedded *task.Scheduler that holds pending jobs in-process and calls
+// back into Enqueue when they're due; unlike the work queue itself, those jobs don't survive
+// a process restart.
+type Backend struct {
+	*task.Scheduler
+
+	cfg  Configuration
+	conn *amqp.Connection
+	ch   *amqp.Channel
+
+	scope tally.Scope
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+
+	wg       sync.WaitGroup
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New dials the broker at cfg.URL, declares the work queue plus the retry/dead-letter
+// topology described in the package doc, and returns a Backend.
+func New(cfg Configuration, scope tally.Scope) (*Backend, error) {
+	if cfg.Queue == "" {
+		cfg.Queue = "fx.tasks"
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Retry.BaseDelay <= 0 {
+		cfg.Retry.BaseDelay = time.Second
+	}
+	if cfg.Retry.MaxDelay <= 0 {
+		cfg.Retry.MaxDelay = time.Minute
+	}
+
+	conn, err := amqp.Dial(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	b := &Backend{
+		cfg:      cfg,
+		conn:     conn,
+		ch:       ch,
+		scope:    scope,
+		handlers: make(map[string]HandlerFunc),
+		stop:     make(chan struct{}),
+	}
+	b.Scheduler = task.NewScheduler(b.Enqueue)
+	if err := b.declareTopology(); err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// declareTopology declares the work queue, the dead-letter queue, and one retry queue per
+// possible attempt number. Each retry queue's `x-dead-letter-exchange` is the default
+// exchange and its `x-dead-letter-routing-key` is the work queue, so a message that sits out
+// its `x-message-ttl` there is routed straight back to the work queue without a consumer.
+func (b *Backend) declareTopology() error {
+	if _, err := b.ch.QueueDeclare(b.cfg.Queue, true, false, false, false, nil); err != nil {
+		return err
+	}
+	if _, err := b.ch.QueueDeclare(b.dlq(), true, false, false, false, nil); err != nil {
+		return err
+	}
+	for attempt := 1; attempt < b.cfg.MaxAttempts; attempt++ {
+		delay := backoff(b.cfg.Retry.BaseDelay, b.cfg.Retry.MaxDelay, attempt)
+		args := amqp.Table{
+			"x-message-ttl":             int64(delay / time.Millisecond),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": b.cfg.Queue,
+		}
+		if _, err := b.ch.QueueDeclare(b.retryQueue(attempt), true, false, false, false, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Backend) dlq() string { return b.cfg.Queue + ".dlq" }
+
+func (b *Backend) retryQueue(attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", b.cfg.Queue, attempt)
+}
+
+// HandleFunc registers h to process tasks enqueued under name. It must be called before
+// Start; handlers registered afterward race with the consumer goroutines reading the map.
+func (b *Backend) HandleFunc(name string, h HandlerFunc) {
+	b.mu.Lock()
+	b.handlers[name] = h
+	b.mu.Unlock()
+}
+
+// Enqueue implements task.Backend by publishing t, persistently, to the work queue.
+func (b *Backend) Enqueue(ctx context.Context, t task.Task) error {
+	return b.publish(b.cfg.Queue, envelope{Name: t.Name, Payload: task.EnqueueContext(ctx, t.Payload)})
+}
+
+func (b *Backend) publish(queue string, e envelope) error {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	err = b.ch.Publish("", queue, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         raw,
+	})
+	if err != nil {
+		return err
+	}
+	b.counter("enqueue").Inc(1)
+	return nil
+}
+
+// Start launches Configuration.Concurrency consumer goroutines against the work queue.
+func (b *Backend) Start() error {
+	deliveries, err := b.ch.Consume(b.cfg.Queue, "", false /* autoAck */, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	b.Scheduler.Start()
+	for i := 0; i < b.cfg.Concurrency; i++ {
+		b.wg.Add(1)
+		go b.worker(deliveries)
+	}
+	return nil
+}
+
+// Stop stops accepting new deliveries onto already-running workers and blocks until any
+// in-flight delivery has been acked, nacked, or republished.
+func (b *Backend) Stop() error {
+	b.Scheduler.Stop()
+	b.stopOnce.Do(func() { close(b.stop) })
+	b.wg.Wait()
+	return b.ch.Close()
+}
+
+func (b *Backend) worker(deliveries <-chan amqp.Delivery) {
+	defer b.wg.Done()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			b.handle(d)
+		}
+	}
+}
+
+func (b *Backend) handle(d amqp.Delivery) {
+	var e envelope
+	if err := json.Unmarshal(d.Body, &e); err != nil {
+		d.Ack(false)
+		return
+	}
+
+	b.mu.RLock()
+	h, ok := b.handlers[e.Name]
+	b.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("amqp backend: no handler registered for task %q", e.Name)
+	} else {
+		ctx := task.ContextWithLogFields(context.Background(), e.Payload)
+		err = b.invoke(ctx, h, e.Payload)
+	}
+
+	if err == nil {
+		d.Ack(false)
+		b.counter("dequeue").Inc(1)
+		return
+	}
+
+	e.Attempts++
+	dlq := e.Attempts >= b.cfg.MaxAttempts
+	queue := b.retryQueue(e.Attempts)
+	if dlq {
+		queue = b.dlq()
+	}
+	if err := b.publish(queue, e); err != nil {
+		// The republish itself didn't land, so leave the delivery unacked: the broker
+		// redelivers it (to this or another worker) instead of it being silently lost.
+		d.Nack(false, true)
+		return
+	}
+
+	// Only ack the original delivery once its retry/DLQ copy is durably published, so a
+	// crash between the two never drops the task.
+	d.Ack(false)
+	if dlq {
+		b.counter("dlq").Inc(1)
+		return
+	}
+	b.counter("retry").Inc(1)
+}
+
+func (b *Backend) invoke(ctx context.Context, h HandlerFunc, payload map[string]interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("amqp backend: handler panicked: %v", r)
+		}
+	}()
+	return h(ctx, payload)
+}
+
+func (b *Backend) counter(name string) tally.Counter {
+	if b.scope == nil {
+		return tally.NoopScope.Counter(name)
+	}
+	return b.scope.Counter(name)
+}
+
+// backoff returns an exponential delay for the given attempt number, capped at maxDelay and
+// jittered by up to 50% so a burst of failures doesn't retry in lockstep.
+func backoff(base, maxDelay time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt)
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+	jitter := time.Duration(rand.Int63n(int64(d)/2 + 1))
+	return d/2 + jitter
+}
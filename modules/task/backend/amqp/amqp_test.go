@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+//
+// This file covers the backend's pure, non-broker logic only: handle's ack-after-publish
+// ordering and the retry/DLQ queue topology both require a live AMQP broker, which this
+// offline test suite has no way to stand up, so they aren't covered here.
+package amqp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoffBoundedByMaxDelay(t *testing.T) {
+	for attempt := 0; attempt < 20; attempt++ {
+		d := backoff(time.Second, time.Minute, attempt)
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		assert.LessOrEqual(t, d, time.Minute)
+	}
+}
+
+func TestBackoffGrowsWithAttempt(t *testing.T) {
+	assert.Less(t, backoff(time.Second, time.Minute, 1), backoffFloor(time.Second, 4))
+}
+
+func backoffFloor(base time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt)
+	return d / 2
+}
+
+func TestDlqAndRetryQueueNaming(t *testing.T) {
+	b := &Backend{cfg: Configuration{Queue: "fx.tasks"}}
+
+	assert.Equal(t, "fx.tasks.dlq", b.dlq())
+	assert.Equal(t, "fx.tasks.retry.1", b.retryQueue(1))
+	assert.Equal(t, "fx.tasks.retry.2", b.retryQueue(2))
+}
+
+func TestEnvelopeJSONRoundTrip(t *testing.T) {
+	e := envelope{Name: "send-report", Payload: map[string]interface{}{"k": "v"}, Attempts: 2}
+
+	raw, err := json.Marshal(e)
+	require.NoError(t, err)
+
+	var got envelope
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, e, got)
+}
+
+func TestBackendInvokeRecoversPanic(t *testing.T) {
+	b := &Backend{}
+	err := b.invoke(context.Background(), func(ctx context.Context, payload map[string]interface{}) error {
+		panic("boom")
+	}, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
+
+func TestBackendInvokePropagatesHandlerError(t *testing.T) {
+	b := &Backend{}
+	want := errors.New("handler failed")
+	err := b.invoke(context.Background(), func(ctx context.Context, payload map[string]interface{}) error {
+		return want
+	}, nil)
+
+	assert.Equal(t, want, err)
+}
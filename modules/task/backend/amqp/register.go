@@ -0,0 +1,41 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package amqp
+
+import (
+	"go.uber.org/fx/modules/task"
+	"go.uber.org/fx/service"
+)
+
+func init() {
+	task.RegisterBackendFactory("amqp", newFromHost)
+}
+
+// newFromHost adapts New to task.BackendFactory, populating Configuration from host's
+// `task.amqp` config key so this package becomes selectable via `task.backend: amqp` once
+// blank-imported.
+func newFromHost(host service.Host) (task.Backend, error) {
+	var cfg Configuration
+	if err := host.Config().Get("task.amqp").PopulateStruct(&cfg); err != nil {
+		return nil, err
+	}
+	return New(cfg, host.Metrics())
+}
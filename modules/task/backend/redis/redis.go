@@ -0,0 +1,455 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package redis implements a task.Backend on top of Redis sorted sets: enqueue is a ZADD
+// scored by "now", delivery is a worker-side ZPOPMIN-style claim, and at-least-once delivery
+// is enforced by re-scoring a claimed task into the future (its visibility timeout) so a
+// reclaim loop can hand it to another worker if the original one never acknowledges it.
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/fx/modules/task"
+
+	"github.com/uber-go/tally"
+	redis "gopkg.in/redis.v5"
+)
+
+// Configuration configures the Redis task backend, typically loaded from a service's YAML
+// config under the `task.redis` key.
+type Configuration struct {
+	Addr              string        `yaml:"addr"`
+	Queue             string        `yaml:"queue"`
+	Concurrency       int           `yaml:"concurrency"`
+	VisibilityTimeout time.Duration `yaml:"visibilityTimeout"`
+	MaxAttempts       int           `yaml:"maxAttempts"`
+	Retry             RetryConfig   `yaml:"retry"`
+}
+
+// RetryConfig controls the exponential backoff applied between delivery attempts.
+type RetryConfig struct {
+	BaseDelay time.Duration `yaml:"baseDelay"`
+	MaxDelay  time.Duration `yaml:"maxDelay"`
+}
+
+// HandlerFunc processes a single task's payload. An error (or panic, which is recovered and
+// treated as an error) causes the task to be retried, up to Configuration.MaxAttempts.
+type HandlerFunc func(ctx context.Context, payload map[string]interface{}) error
+
+// entry is the JSON envelope stored as a sorted-set member.
+type entry struct {
+	ID       string                 `json:"id"`
+	Name     string                 `json:"name"`
+	Payload  map[string]interface{} `json:"payload"`
+	Attempts int                    `json:"attempts"`
+}
+
+// schedEntry is the JSON envelope stored as a member of the recurring-job sorted set.
+type schedEntry struct {
+	ID      string                 `json:"id"`
+	Name    string                 `json:"name"`
+	Payload map[string]interface{} `json:"payload"`
+	Spec    string                 `json:"spec"`
+}
+
+// popDueSchedule atomically finds and removes the single most-due member of a sorted set
+// scored by fire time, so that concurrent pollers across replicas never both claim the same
+// recurring job's occurrence.
+var popDueSchedule = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #due == 0 then
+	return false
+end
+redis.call('ZREM', KEYS[1], due[1])
+return due[1]
+`)
+
+// claimDueWork atomically finds the single most-due member of the work queue and re-scores it
+// to visibleUntil (ARGV[2]) in the same round trip, so two worker goroutines (or two replicas)
+// racing claimAndProcessOne can never both read the same due member before either claims it.
+var claimDueWork = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #due == 0 then
+	return false
+end
+redis.call('ZADD', KEYS[1], ARGV[2], due[1])
+return due[1]
+`)
+
+// claimSchedule atomically HSETNX's id (ARGV[1]) onto the idempotency hash (KEYS[1]) and, only
+// if that claim was newly won, ZADDs raw (ARGV[3]) into the recurring-job sorted set (KEYS[2])
+// scored by fireTime (ARGV[2]) in the same round trip. Doing both in one script means a failure
+// between the two can never happen: either the whole registration lands, or the claim is never
+// taken and a later Schedule call for the same (spec, t.Name) retries it from scratch.
+var claimSchedule = redis.NewScript(`
+local claimed = redis.call('HSETNX', KEYS[1], ARGV[1], '1')
+if claimed == 1 then
+	redis.call('ZADD', KEYS[2], ARGV[2], ARGV[3])
+end
+return claimed
+`)
+
+// Backend is a task.Backend backed by Redis. Construct it with New and register at least one
+// handler with HandleFunc before Start.
+type Backend struct {
+	cfg    Configuration
+	client *redis.Client
+	scope  tally.Scope
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// New dials Redis per cfg and returns a Backend. The connection is lazy (redis.v5 dials on
+// first command), so New returning without error doesn't guarantee Redis is reachable.
+func New(cfg Configuration, scope tally.Scope) (*Backend, error) {
+	if cfg.Queue == "" {
+		cfg.Queue = "fx:tasks"
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = 30 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Retry.BaseDelay <= 0 {
+		cfg.Retry.BaseDelay = time.Second
+	}
+	if cfg.Retry.MaxDelay <= 0 {
+		cfg.Retry.MaxDelay = time.Minute
+	}
+
+	return &Backend{
+		cfg:      cfg,
+		client:   redis.NewClient(&redis.Options{Addr: cfg.Addr}),
+		scope:    scope,
+		handlers: make(map[string]HandlerFunc),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// HandleFunc registers h to process tasks enqueued under name. It must be called before
+// Start; handlers registered afterward race with the worker goroutines reading the map.
+func (b *Backend) HandleFunc(name string, h HandlerFunc) {
+	b.mu.Lock()
+	b.handlers[name] = h
+	b.mu.Unlock()
+}
+
+// Enqueue implements task.Backend by ZADDing t onto the queue, scored to be immediately due.
+func (b *Backend) Enqueue(ctx context.Context, t task.Task) error {
+	return b.schedule(ctx, t, time.Now())
+}
+
+// EnqueueIn implements task.Backend by ZADDing t onto the queue, scored to become due after
+// delay.
+func (b *Backend) EnqueueIn(ctx context.Context, t task.Task, delay time.Duration) error {
+	return b.schedule(ctx, t, time.Now().Add(delay))
+}
+
+// EnqueueAt implements task.Backend by ZADDing t onto the queue, scored to become due at
+// fireTime.
+func (b *Backend) EnqueueAt(ctx context.Context, t task.Task, fireTime time.Time) error {
+	return b.schedule(ctx, t, fireTime)
+}
+
+// Schedule implements task.Backend by registering t to be enqueued on the work queue every
+// time spec's cron expression matches. The registration itself lives in Redis (claimSchedule
+// atomically HSETNXs an idempotency key derived from spec and t.Name and, only on a newly won
+// claim, ZADDs into the recurring-job sorted set), so calling Schedule again for the same
+// spec/t.Name — even from a different process, e.g. after a retried request — is a no-op that
+// returns the already-registered id, and a claim can never be won without its job actually
+// landing in the sorted set.
+func (b *Backend) Schedule(spec string, t task.Task) (task.ScheduleID, error) {
+	fireTime, err := task.NextCronFire(spec, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	id := idempotencyID(spec, t.Name)
+	raw, err := json.Marshal(schedEntry{ID: id, Name: t.Name, Payload: t.Payload, Spec: spec})
+	if err != nil {
+		return "", err
+	}
+	if err := claimSchedule.Run(b.client, []string{b.schedIDsKey(), b.schedKey()},
+		id, formatScore(float64(fireTime.UnixNano())), raw).Err(); err != nil {
+		return "", err
+	}
+	return task.ScheduleID(id), nil
+}
+
+func (b *Backend) schedKey() string    { return b.cfg.Queue + ":sched" }
+func (b *Backend) schedIDsKey() string { return b.cfg.Queue + ":sched:ids" }
+
+func (b *Backend) schedule(ctx context.Context, t task.Task, fireTime time.Time) error {
+	e := entry{ID: newID(), Name: t.Name, Payload: task.EnqueueContext(ctx, t.Payload)}
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := b.client.ZAdd(b.cfg.Queue, redis.Z{
+		Score:  float64(fireTime.UnixNano()),
+		Member: raw,
+	}).Err(); err != nil {
+		return err
+	}
+	b.counter("enqueue").Inc(1)
+	return nil
+}
+
+// Start launches Configuration.Concurrency worker goroutines that poll the queue for due
+// tasks and dispatch them to their registered HandlerFunc, plus a single poller that fires
+// due recurring jobs registered via Schedule onto the work queue.
+func (b *Backend) Start() error {
+	for i := 0; i < b.cfg.Concurrency; i++ {
+		b.wg.Add(1)
+		go b.worker()
+	}
+	b.wg.Add(1)
+	go b.schedulePoller()
+	return nil
+}
+
+// Stop signals the worker and scheduler goroutines to finish their current task and return,
+// then blocks until they do.
+func (b *Backend) Stop() error {
+	b.stopOnce.Do(func() { close(b.stop) })
+	b.wg.Wait()
+	return nil
+}
+
+func (b *Backend) worker() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			for b.claimAndProcessOne() {
+				select {
+				case <-b.stop:
+					return
+				default:
+				}
+			}
+		}
+	}
+}
+
+// schedulePoller periodically pops due entries off the recurring-job sorted set via
+// popDueSchedule, the single atomic claim that keeps concurrent pollers (across replicas of
+// this same service) from double-firing the same occurrence, re-registers each for its cron
+// spec's next occurrence, and enqueues the actual task onto the work queue.
+func (b *Backend) schedulePoller() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			for b.fireDueSchedule() {
+				select {
+				case <-b.stop:
+					return
+				default:
+				}
+			}
+		}
+	}
+}
+
+func (b *Backend) fireDueSchedule() bool {
+	now := time.Now()
+	res, err := popDueSchedule.Run(b.client, []string{b.schedKey()}, formatScore(float64(now.UnixNano()))).Result()
+	if err != nil || res == nil {
+		return false
+	}
+	raw, ok := res.(string)
+	if !ok {
+		return false
+	}
+
+	var e schedEntry
+	if err := json.Unmarshal([]byte(raw), &e); err != nil {
+		return true
+	}
+
+	if next, err := task.NextCronFire(e.Spec, now); err == nil {
+		reraw, err := json.Marshal(e)
+		if err == nil {
+			b.client.ZAdd(b.schedKey(), redis.Z{Score: float64(next.UnixNano()), Member: reraw})
+		}
+	}
+
+	b.schedule(context.Background(), task.Task{Name: e.Name, Payload: e.Payload}, now)
+	return true
+}
+
+// claimAndProcessOne atomically claims the single most-due task (if any), via claimDueWork, by
+// re-scoring it past now+VisibilityTimeout in the same Redis round trip that finds it, so two
+// worker goroutines (Configuration.Concurrency > 1) or two replicas polling at once never both
+// read the same due member before either claims it; a crashed worker's claim eventually expires
+// and the task becomes claimable again, via that same script, once its visibility passes. It
+// returns false when there was nothing due to claim.
+func (b *Backend) claimAndProcessOne() bool {
+	now := time.Now()
+	visibleUntil := float64(now.Add(b.cfg.VisibilityTimeout).UnixNano())
+	res, err := claimDueWork.Run(b.client, []string{b.cfg.Queue}, formatScore(float64(now.UnixNano())), formatScore(visibleUntil)).Result()
+	if err != nil || res == nil {
+		return false
+	}
+	raw, ok := res.(string)
+	if !ok {
+		return false
+	}
+
+	b.process(raw)
+	return true
+}
+
+func (b *Backend) process(raw string) {
+	var e entry
+	if err := json.Unmarshal([]byte(raw), &e); err != nil {
+		b.client.ZRem(b.cfg.Queue, raw)
+		return
+	}
+
+	b.mu.RLock()
+	h, ok := b.handlers[e.Name]
+	b.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = errUnknownHandler(e.Name)
+	} else {
+		ctx := task.ContextWithLogFields(context.Background(), e.Payload)
+		err = b.invoke(ctx, h, e.Payload)
+	}
+
+	b.client.ZRem(b.cfg.Queue, raw)
+	if err == nil {
+		b.counter("dequeue").Inc(1)
+		return
+	}
+
+	e.Attempts++
+	if e.Attempts >= b.cfg.MaxAttempts {
+		b.deadLetter(e)
+		return
+	}
+	b.counter("retry").Inc(1)
+	b.requeueWithBackoff(e)
+}
+
+func (b *Backend) invoke(ctx context.Context, h HandlerFunc, payload map[string]interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errPanic(r)
+		}
+	}()
+	return h(ctx, payload)
+}
+
+func (b *Backend) requeueWithBackoff(e entry) {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	delay := backoff(b.cfg.Retry.BaseDelay, b.cfg.Retry.MaxDelay, e.Attempts)
+	b.client.ZAdd(b.cfg.Queue, redis.Z{
+		Score:  float64(time.Now().Add(delay).UnixNano()),
+		Member: raw,
+	})
+}
+
+func (b *Backend) deadLetter(e entry) {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	b.client.LPush(b.cfg.Queue+":dlq", raw)
+	b.counter("dlq").Inc(1)
+}
+
+func (b *Backend) counter(name string) tally.Counter {
+	if b.scope == nil {
+		return tally.NoopScope.Counter(name)
+	}
+	return b.scope.Counter(name)
+}
+
+// backoff returns an exponential delay for the given attempt number, capped at maxDelay and
+// jittered by up to 50% to avoid every retry of a batch landing on the same tick.
+func backoff(base, maxDelay time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt)
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+	jitter := time.Duration(rand.Int63n(int64(d)/2 + 1))
+	return d/2 + jitter
+}
+
+func formatScore(f float64) string {
+	return strconv.FormatFloat(f, 'f', 0, 64)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// idempotencyID derives a stable id from a Schedule call's (spec, task name) pair, so
+// repeated or retried Schedule calls for the same recurring job resolve to the same id
+// instead of registering a duplicate.
+func idempotencyID(spec, name string) string {
+	sum := sha1.Sum([]byte(spec + "|" + name))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func errUnknownHandler(name string) error {
+	return fmt.Errorf("redis backend: no handler registered for task %q", name)
+}
+
+func errPanic(r interface{}) error {
+	return fmt.Errorf("redis backend: handler panicked: %v", r)
+}
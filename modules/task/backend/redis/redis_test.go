@@ -0,0 +1,105 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+//
+// This file covers the backend's pure, non-network logic only: claim-atomicity (the
+// claimSchedule/claimDueWork Lua scripts) and visibility-timeout reclaim both require a live
+// Redis instance, which this offline test suite has no way to stand up, so they aren't covered
+// here.
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoffBoundedByMaxDelay(t *testing.T) {
+	for attempt := 0; attempt < 20; attempt++ {
+		d := backoff(time.Second, time.Minute, attempt)
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		assert.LessOrEqual(t, d, time.Minute)
+	}
+}
+
+func TestBackoffGrowsWithAttempt(t *testing.T) {
+	// backoff's jitter term only ever adds up to half of d, so its floor (d/2) is a
+	// deterministic lower bound: attempt 1 never exceeds 2s, while attempt 4 never drops
+	// below its 8s floor, so the comparison can't flake on the random component.
+	assert.Less(t, backoff(time.Second, time.Minute, 1), backoffFloor(time.Second, 4))
+}
+
+func backoffFloor(base time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt)
+	return d / 2
+}
+
+func TestFormatScore(t *testing.T) {
+	assert.Equal(t, "1700000000000000000", formatScore(1700000000000000000))
+}
+
+func TestNewIDIsUnique(t *testing.T) {
+	a := newID()
+	b := newID()
+	assert.NotEqual(t, a, b)
+	assert.Len(t, a, 32)
+}
+
+func TestIdempotencyIDIsDeterministic(t *testing.T) {
+	a := idempotencyID("* * * * *", "send-report")
+	b := idempotencyID("* * * * *", "send-report")
+	assert.Equal(t, a, b)
+}
+
+func TestIdempotencyIDDistinguishesSpecAndName(t *testing.T) {
+	base := idempotencyID("* * * * *", "send-report")
+	diffSpec := idempotencyID("0 * * * *", "send-report")
+	diffName := idempotencyID("* * * * *", "send-digest")
+	assert.NotEqual(t, base, diffSpec)
+	assert.NotEqual(t, base, diffName)
+}
+
+func TestBackendInvokeRecoversPanic(t *testing.T) {
+	b := &Backend{}
+	err := b.invoke(context.Background(), func(ctx context.Context, payload map[string]interface{}) error {
+		panic("boom")
+	}, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
+
+func TestBackendInvokePropagatesHandlerError(t *testing.T) {
+	b := &Backend{}
+	want := errors.New("handler failed")
+	err := b.invoke(context.Background(), func(ctx context.Context, payload map[string]interface{}) error {
+		return want
+	}, nil)
+
+	assert.Equal(t, want, err)
+}
+
+func TestErrUnknownHandlerMentionsName(t *testing.T) {
+	err := errUnknownHandler("send-report")
+	assert.Contains(t, err.Error(), "send-report")
+}
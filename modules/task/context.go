@@ -0,0 +1,68 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package task
+
+import (
+	"context"
+
+	"go.uber.org/fx/ulog"
+)
+
+// _logFieldsKey is the payload key under which the enqueuing caller's logger fields are
+// stashed by EnqueueContext, so the worker side can recover them via ContextWithLogFields.
+const _logFieldsKey = "_ulogFields"
+
+// EnqueueContext copies the logger fields carried on ctx (as set up by, e.g., uhttp's log
+// filter) onto payload, so that a worker processing the resulting task can reconstruct a
+// logger with the same request-scoped context via ContextWithLogFields. Backends call this
+// when building the envelope for an enqueued task; it is a no-op if ctx carries no fields.
+func EnqueueContext(ctx context.Context, payload map[string]interface{}) map[string]interface{} {
+	fields := ulog.Logger(ctx).Fields()
+	if len(fields) == 0 {
+		return payload
+	}
+
+	if payload == nil {
+		payload = make(map[string]interface{})
+	}
+	payload[_logFieldsKey] = ulog.FieldsToMap(fields)
+	return payload
+}
+
+// ContextWithLogFields reconstructs the originating caller's logger fields, if any, from a
+// task payload produced by EnqueueContext, and returns a ctx that ulog.Logger resolves to a
+// Log carrying them. Worker implementations should call this before invoking the task so that
+// ulog.Logger(ctx) inside the handler reflects the caller's context (request id, trace id,
+// etc.) rather than the worker's own.
+func ContextWithLogFields(ctx context.Context, payload map[string]interface{}) context.Context {
+	raw, ok := payload[_logFieldsKey]
+	if !ok {
+		return ctx
+	}
+
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return ctx
+	}
+
+	log := ulog.Logger(ctx).With(ulog.FieldsToKeyvals(ulog.MapToFields(m))...)
+	return ulog.NewLogContext(ctx, log)
+}
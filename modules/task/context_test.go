@@ -0,0 +1,81 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package task
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/fx/ulog"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnqueueContextStashesFields(t *testing.T) {
+	log := ulog.Builder().WithConfiguration(&ulog.Configuration{}).Build().With("requestID", "req-1")
+	ctx := ulog.NewLogContext(context.Background(), log)
+
+	payload := EnqueueContext(ctx, map[string]interface{}{"k": "v"})
+
+	require.Contains(t, payload, _logFieldsKey)
+	require.Contains(t, payload, "k")
+}
+
+func TestEnqueueContextNoopWithoutFields(t *testing.T) {
+	payload := map[string]interface{}{"k": "v"}
+
+	got := EnqueueContext(context.Background(), payload)
+
+	assert.NotContains(t, got, _logFieldsKey)
+	assert.Equal(t, payload, got)
+}
+
+func TestEnqueueContextNoopWithNilPayload(t *testing.T) {
+	got := EnqueueContext(context.Background(), nil)
+
+	assert.Nil(t, got)
+}
+
+func TestContextWithLogFieldsRoundTrips(t *testing.T) {
+	log := ulog.Builder().WithConfiguration(&ulog.Configuration{}).Build().With("requestID", "req-1")
+	enqueueCtx := ulog.NewLogContext(context.Background(), log)
+	payload := EnqueueContext(enqueueCtx, nil)
+
+	workerCtx := ContextWithLogFields(context.Background(), payload)
+
+	fields := ulog.Logger(workerCtx).Fields()
+	var found bool
+	for _, f := range fields {
+		if f.Key == "requestID" && f.Value == "req-1" {
+			found = true
+		}
+	}
+	assert.True(t, found, "expected requestID field to survive the round trip")
+}
+
+func TestContextWithLogFieldsNoopWithoutPayloadKey(t *testing.T) {
+	ctx := context.Background()
+
+	got := ContextWithLogFields(ctx, map[string]interface{}{"other": "value"})
+
+	assert.Equal(t, ctx, got)
+}
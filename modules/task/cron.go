@@ -0,0 +1,150 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSpec is a parsed standard 5-field cron expression: minute, hour, day-of-month, month,
+// day-of-week (0-6, Sunday is 0). Each field accepts "*", a single value, a comma-separated
+// list, an inclusive "lo-hi" range, or a "/step" suffix on any of the above.
+//
+// domRestricted and dowRestricted record whether the dom/dow fields were other than "*", since standard
+// cron semantics treat those two fields specially: if only one is restricted, matches requires
+// both (the unrestricted one is trivially satisfied); if both are restricted, matches requires
+// either (e.g. "0 0 1 * 1" fires on the 1st of the month OR every Monday, not only when the
+// 1st falls on a Monday).
+type cronSpec struct {
+	minute, hour, dom, month, dow fieldSet
+	domRestricted, dowRestricted  bool
+}
+
+type fieldSet map[int]bool
+
+// parseCronSpec parses spec into a cronSpec, or returns an error describing the first
+// malformed field.
+func parseCronSpec(spec string) (*cronSpec, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("task: cron spec must have 5 fields (minute hour dom month dow), got %d in %q", len(fields), spec)
+	}
+
+	parsed := make([]fieldSet, 5)
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+	for i, f := range fields {
+		set, err := parseCronField(f, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = set
+	}
+	return &cronSpec{
+		minute: parsed[0], hour: parsed[1], dom: parsed[2], month: parsed[3], dow: parsed[4],
+		domRestricted: fields[2] != "*", dowRestricted: fields[4] != "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (fieldSet, error) {
+	set := make(fieldSet)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		rangePart := part
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("task: invalid step in cron field %q", part)
+			}
+			step, rangePart = n, part[:idx]
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bound := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bound[0]); err != nil {
+				return nil, fmt.Errorf("task: invalid cron field %q", part)
+			}
+			if hi, err = strconv.Atoi(bound[1]); err != nil {
+				return nil, fmt.Errorf("task: invalid cron field %q", part)
+			}
+		default:
+			v, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return nil, fmt.Errorf("task: invalid cron field %q", part)
+			}
+			lo, hi = v, v
+		}
+
+		for v := lo; v <= hi; v += step {
+			if v < min || v > max {
+				return nil, fmt.Errorf("task: cron field value %d outside [%d,%d] in %q", v, min, max, part)
+			}
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// NextCronFire parses spec, a standard 5-field cron expression, and returns the first
+// minute-aligned instant after from that it matches. Backend implementations with native
+// support for recurring jobs (e.g. the redis backend's sorted-set poller) use this to compute
+// each re-fire time without reimplementing cron parsing themselves; Scheduler uses it
+// internally for backends that have no such native support.
+func NextCronFire(spec string, from time.Time) (time.Time, error) {
+	cs, err := parseCronSpec(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cs.next(from), nil
+}
+
+func (c *cronSpec) matches(t time.Time) bool {
+	if !c.minute[t.Minute()] || !c.hour[t.Hour()] || !c.month[int(t.Month())] {
+		return false
+	}
+
+	domMatch, dowMatch := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	if c.domRestricted && c.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// next returns the first minute-aligned instant strictly after from that matches c. A cap of
+// four years of minutes guards against specs whose dom/month/dow combination can never be
+// satisfied (e.g. "0 0 31 2 *"), returning a best-effort hour-out fallback in that case rather
+// than spinning forever.
+func (c *cronSpec) next(from time.Time) time.Time {
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	for i := 0; i < 4*366*24*60; i++ {
+		if c.matches(t) {
+			return t
+		}
+		t = t.Add(time.Minute)
+	}
+	return from.Add(time.Hour)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		min, max int
+		want     fieldSet
+		wantErr  bool
+	}{
+		{"wildcard", "*", 0, 4, fieldSet{0: true, 1: true, 2: true, 3: true, 4: true}, false},
+		{"single value", "3", 0, 5, fieldSet{3: true}, false},
+		{"list", "1,3,5", 0, 5, fieldSet{1: true, 3: true, 5: true}, false},
+		{"range", "1-3", 0, 5, fieldSet{1: true, 2: true, 3: true}, false},
+		{"step", "*/2", 0, 5, fieldSet{0: true, 2: true, 4: true}, false},
+		{"range with step", "1-5/2", 0, 5, fieldSet{1: true, 3: true, 5: true}, false},
+		{"value outside bounds", "6", 0, 5, nil, true},
+		{"not a number", "abc", 0, 5, nil, true},
+		{"zero step", "*/0", 0, 5, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCronField(tt.field, tt.min, tt.max)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseCronSpecWrongFieldCount(t *testing.T) {
+	_, err := parseCronSpec("* * *")
+	assert.Error(t, err)
+}
+
+func TestCronSpecMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{
+			name: "exact minute matches",
+			spec: "30 9 * * *",
+			at:   time.Date(2026, 7, 30, 9, 30, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			name: "different minute doesn't match",
+			spec: "30 9 * * *",
+			at:   time.Date(2026, 7, 30, 9, 31, 0, 0, time.UTC),
+			want: false,
+		},
+		{
+			name: "dom and dow both restricted: dom side matches",
+			spec: "0 0 1 * 1",
+			at:   time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC), // Saturday the 1st
+			want: true,
+		},
+		{
+			name: "dom and dow both restricted: dow side matches",
+			spec: "0 0 1 * 1",
+			at:   time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC), // Monday the 3rd
+			want: true,
+		},
+		{
+			name: "dom and dow both restricted: neither side matches",
+			spec: "0 0 1 * 1",
+			at:   time.Date(2026, 8, 4, 0, 0, 0, 0, time.UTC), // Tuesday the 4th
+			want: false,
+		},
+		{
+			name: "only dom restricted requires dom",
+			spec: "0 0 1 * *",
+			at:   time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC), // Monday the 3rd, not the 1st
+			want: false,
+		},
+		{
+			name: "only dow restricted requires dow",
+			spec: "0 0 * * 1",
+			at:   time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC), // the 1st, but not a Monday
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := parseCronSpec(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cs.matches(tt.at))
+		})
+	}
+}
+
+func TestNextCronFire(t *testing.T) {
+	from := time.Date(2026, 7, 30, 9, 29, 0, 0, time.UTC)
+
+	next, err := NextCronFire("30 9 * * *", from)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 7, 30, 9, 30, 0, 0, time.UTC), next)
+
+	next, err = NextCronFire("0 0 * * *", from)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 7, 31, 0, 0, 0, 0, time.UTC), next)
+
+	_, err = NextCronFire("not a cron spec", from)
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package task
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/fx/service"
+)
+
+// BackendFactory builds a named kind of Backend (e.g. "redis", "amqp") from host, typically by
+// populating that kind's own Configuration struct from a host.Config() key and dialing it.
+// Backend packages that want to be selectable via the `task.backend` config key register
+// themselves under their name with RegisterBackendFactory, normally from an init func — see
+// modules/task/backend/redis and modules/task/backend/amqp.
+type BackendFactory func(host service.Host) (Backend, error)
+
+var (
+	_factoriesMu sync.RWMutex
+	_factories   = map[string]BackendFactory{
+		"inmem": func(service.Host) (Backend, error) { return NopBackend{}, nil },
+	}
+)
+
+// RegisterBackendFactory makes factory selectable under name via the `task.backend` config key
+// read by NewModuleFromConfig/NewNamedModuleFromConfig. Call it from an init func so that
+// blank-importing a backend package (e.g. `_ "go.uber.org/fx/modules/task/backend/redis"`) is
+// enough to make that name available, without the task package itself having to import it
+// (which would be a cycle, since every backend package imports task for the Backend interface).
+func RegisterBackendFactory(name string, factory BackendFactory) {
+	_factoriesMu.Lock()
+	_factories[name] = factory
+	_factoriesMu.Unlock()
+}
+
+func backendFactory(name string) (BackendFactory, bool) {
+	_factoriesMu.RLock()
+	defer _factoriesMu.RUnlock()
+	f, ok := _factories[name]
+	return f, ok
+}
+
+// moduleConfig is the `task` config block NewModuleFromConfig reads to pick a backend.
+type moduleConfig struct {
+	Backend string `yaml:"backend"`
+}
+
+// NewModuleFromConfig creates an async task queue module whose backend is selected by the
+// `task.backend` config key (e.g. "redis", "amqp", "inmem"; defaulting to "inmem" if unset)
+// rather than a hand-supplied BackendCreateFunc, registering it under DefaultBackendName. The
+// chosen kind must have a factory registered via RegisterBackendFactory — blank-import its
+// package to make it available.
+func NewModuleFromConfig() service.ModuleCreateFunc {
+	return NewNamedModuleFromConfig(DefaultBackendName)
+}
+
+// NewNamedModuleFromConfig is NewModuleFromConfig, except the backend is registered under name
+// rather than DefaultBackendName, for hosts wiring up more than one task module.
+func NewNamedModuleFromConfig(name string) service.ModuleCreateFunc {
+	return NewNamedModule(name, createFuncFromConfig)
+}
+
+func createFuncFromConfig(host service.Host) (Backend, error) {
+	var cfg moduleConfig
+	if err := host.Config().Get("task").PopulateStruct(&cfg); err != nil {
+		return nil, err
+	}
+
+	kind := cfg.Backend
+	if kind == "" {
+		kind = "inmem"
+	}
+	factory, ok := backendFactory(kind)
+	if !ok {
+		return nil, fmt.Errorf("task: no backend registered for task.backend %q", kind)
+	}
+	return factory(host)
+}
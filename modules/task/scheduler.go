@@ -0,0 +1,216 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package task
+
+import (
+	"container/heap"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// scheduledJob is one pending EnqueueIn/EnqueueAt/Schedule entry. spec is nil for a one-shot
+// EnqueueIn/EnqueueAt job and set for a recurring Schedule job, which requeues itself for its
+// spec's next occurrence each time it fires.
+type scheduledJob struct {
+	task   Task
+	fireAt time.Time
+	spec   *cronSpec
+	id     ScheduleID
+	index  int
+}
+
+// jobHeap is a container/heap.Interface min-heap ordered by fireAt, so the next due job is
+// always jobHeap[0].
+type jobHeap []*scheduledJob
+
+func (h jobHeap) Len() int            { return len(h) }
+func (h jobHeap) Less(i, j int) bool  { return h[i].fireAt.Before(h[j].fireAt) }
+func (h jobHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i]; h[i].index = i; h[j].index = j }
+func (h *jobHeap) Push(x interface{}) {
+	j := x.(*scheduledJob)
+	j.index = len(*h)
+	*h = append(*h, j)
+}
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	j := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return j
+}
+
+// Scheduler is an embeddable in-process implementation of the delayed and recurring halves of
+// Backend (EnqueueIn, EnqueueAt, Schedule) for backends whose underlying transport has no
+// native support for either, such as amqp.Backend. Pending jobs sit in a min-heap keyed by
+// fire time; a single goroutine sleeps on a time.Timer reset to the next job's fire time, so
+// waking costs O(log n) regardless of how many jobs are pending, and fires each due job into
+// enqueue, the embedding backend's own immediate Enqueue.
+type Scheduler struct {
+	enqueue func(ctx context.Context, t Task) error
+
+	mu    sync.Mutex
+	jobs  jobHeap
+	byKey map[string]ScheduleID
+
+	timer *time.Timer
+	wake  chan struct{}
+	stop  chan struct{}
+}
+
+// NewScheduler returns a Scheduler that fires due jobs into enqueue. Call Start before
+// EnqueueIn/EnqueueAt/Schedule will actually be delivered, and Stop to release its goroutine.
+func NewScheduler(enqueue func(ctx context.Context, t Task) error) *Scheduler {
+	return &Scheduler{
+		enqueue: enqueue,
+		byKey:   make(map[string]ScheduleID),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start launches the goroutine that waits for and fires due jobs.
+func (s *Scheduler) Start() {
+	go s.run()
+}
+
+// Stop releases the scheduler's goroutine. Jobs still pending in the heap are dropped.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+// EnqueueIn implements the corresponding Backend method by queuing t to fire after delay.
+func (s *Scheduler) EnqueueIn(ctx context.Context, t Task, delay time.Duration) error {
+	return s.EnqueueAt(ctx, t, time.Now().Add(delay))
+}
+
+// EnqueueAt implements the corresponding Backend method by queuing t to fire at fireTime. The
+// caller's logger fields are stashed onto t.Payload now, at registration time, since by the
+// time t actually fires the goroutine in run has no access to ctx to stash them from then.
+func (s *Scheduler) EnqueueAt(ctx context.Context, t Task, fireTime time.Time) error {
+	t.Payload = EnqueueContext(ctx, t.Payload)
+	s.push(&scheduledJob{task: t, fireAt: fireTime})
+	return nil
+}
+
+// Schedule implements the corresponding Backend method. Calling it again with the same spec
+// and t.Name returns the ScheduleID already registered for that pair rather than registering
+// a duplicate recurring job.
+func (s *Scheduler) Schedule(spec string, t Task) (ScheduleID, error) {
+	cs, err := parseCronSpec(spec)
+	if err != nil {
+		return "", err
+	}
+
+	key := spec + "|" + t.Name
+	s.mu.Lock()
+	if id, ok := s.byKey[key]; ok {
+		s.mu.Unlock()
+		return id, nil
+	}
+	id := ScheduleID(idempotencyKey(key))
+	s.byKey[key] = id
+	s.mu.Unlock()
+
+	s.push(&scheduledJob{task: t, fireAt: cs.next(time.Now()), spec: cs, id: id})
+	return id, nil
+}
+
+func (s *Scheduler) push(j *scheduledJob) {
+	s.mu.Lock()
+	heap.Push(&s.jobs, j)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) run() {
+	s.timer = time.NewTimer(time.Hour)
+	defer s.timer.Stop()
+
+	for {
+		s.resetTimer()
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		case <-s.timer.C:
+			s.fireDue()
+		}
+	}
+}
+
+// resetTimer points the timer at the heap's new soonest fire time, draining any pending fire
+// from the previous duration first so Reset doesn't race with an already-fired timer.
+func (s *Scheduler) resetTimer() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.timer.Stop() {
+		select {
+		case <-s.timer.C:
+		default:
+		}
+	}
+	if len(s.jobs) == 0 {
+		s.timer.Reset(time.Hour)
+		return
+	}
+	if d := time.Until(s.jobs[0].fireAt); d > 0 {
+		s.timer.Reset(d)
+	} else {
+		s.timer.Reset(0)
+	}
+}
+
+// fireDue pops and enqueues every job due at or before now, requeuing recurring ones for
+// their spec's next occurrence.
+func (s *Scheduler) fireDue() {
+	now := time.Now()
+	for {
+		s.mu.Lock()
+		if len(s.jobs) == 0 || s.jobs[0].fireAt.After(now) {
+			s.mu.Unlock()
+			return
+		}
+		j := heap.Pop(&s.jobs).(*scheduledJob)
+		if j.spec != nil {
+			heap.Push(&s.jobs, &scheduledJob{task: j.task, fireAt: j.spec.next(now), spec: j.spec, id: j.id})
+		}
+		s.mu.Unlock()
+
+		s.enqueue(context.Background(), j.task)
+	}
+}
+
+// idempotencyKey derives a stable ScheduleID from a Schedule call's (spec, task name) pair,
+// so retried or repeated Schedule calls for the same recurring job resolve to the same id.
+func idempotencyKey(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])[:16]
+}
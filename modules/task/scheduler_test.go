@@ -0,0 +1,93 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package task
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobHeapOrdersByFireTime(t *testing.T) {
+	now := time.Now()
+	h := &jobHeap{
+		{task: Task{Name: "c"}, fireAt: now.Add(3 * time.Second)},
+		{task: Task{Name: "a"}, fireAt: now.Add(1 * time.Second)},
+		{task: Task{Name: "b"}, fireAt: now.Add(2 * time.Second)},
+	}
+	heap.Init(h)
+
+	var order []string
+	for h.Len() > 0 {
+		order = append(order, heap.Pop(h).(*scheduledJob).task.Name)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, order)
+}
+
+func TestSchedulerFiresJobsInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var fired []string
+
+	s := NewScheduler(func(ctx context.Context, tsk Task) error {
+		mu.Lock()
+		fired = append(fired, tsk.Name)
+		mu.Unlock()
+		return nil
+	})
+	s.Start()
+	defer s.Stop()
+
+	require.NoError(t, s.EnqueueIn(context.Background(), Task{Name: "second"}, 20*time.Millisecond))
+	require.NoError(t, s.EnqueueIn(context.Background(), Task{Name: "first"}, 5*time.Millisecond))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(fired) == 2
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"first", "second"}, fired)
+}
+
+func TestSchedulerScheduleIsIdempotent(t *testing.T) {
+	s := NewScheduler(func(ctx context.Context, tsk Task) error { return nil })
+
+	id1, err := s.Schedule("0 0 * * *", Task{Name: "daily"})
+	require.NoError(t, err)
+	id2, err := s.Schedule("0 0 * * *", Task{Name: "daily"})
+	require.NoError(t, err)
+
+	assert.Equal(t, id1, id2)
+}
+
+func TestSchedulerScheduleRejectsInvalidSpec(t *testing.T) {
+	s := NewScheduler(func(ctx context.Context, tsk Task) error { return nil })
+
+	_, err := s.Schedule("not a spec", Task{Name: "daily"})
+	assert.Error(t, err)
+}
@@ -30,17 +30,13 @@ import (
 	"github.com/uber-go/tally"
 )
 
-type globalBackend struct {
-	backend Backend
-	sync.RWMutex
-}
+// DefaultBackendName is the registry key NewModule registers a backend under when the
+// service only has one, so that existing callers of GlobalBackend() keep working unchanged.
+const DefaultBackendName = "default"
 
 var (
-	_globalBackendMu sync.RWMutex
-	_globalBackend   Backend = &NopBackend{}
-	_asyncMod        service.Module
-	_asyncModErr     error
-	_once            sync.Once
+	_backendsMu sync.RWMutex
+	_backends   = map[string]Backend{DefaultBackendName: NopBackend{}}
 )
 
 // SetupTaskMetrics sets up default counters and timers for task execution
@@ -48,42 +44,58 @@ func SetupTaskMetrics(scope tally.Scope) {
 	stats.SetupTaskMetrics(scope)
 }
 
-// GlobalBackend returns global instance of the backend
-// TODO (madhu): Make work with multiple backends
+// RegisterBackend makes backend available under name for later lookup via GlobalBackendNamed
+// (and, if name is DefaultBackendName, via GlobalBackend). It is called by newAsyncModule for
+// every module instance NewModule creates; services wiring up more than one named backend
+// should give each a distinct name via NewNamedModule.
+func RegisterBackend(name string, backend Backend) {
+	_backendsMu.Lock()
+	_backends[name] = backend
+	_backendsMu.Unlock()
+}
+
+// GlobalBackend returns the backend registered under DefaultBackendName, or a NopBackend if
+// the task module hasn't been installed.
 func GlobalBackend() Backend {
-	_globalBackendMu.RLock()
-	defer _globalBackendMu.RUnlock()
-	return _globalBackend
+	return GlobalBackendNamed(DefaultBackendName)
+}
+
+// GlobalBackendNamed returns the backend registered under name, or a NopBackend if nothing is
+// registered under it yet.
+func GlobalBackendNamed(name string) Backend {
+	_backendsMu.RLock()
+	defer _backendsMu.RUnlock()
+	if b, ok := _backends[name]; ok {
+		return b
+	}
+	return NopBackend{}
 }
 
-// NewModule creates an async task queue module
+// NewModule creates an async task queue module, registering its backend under
+// DefaultBackendName.
 func NewModule(createFunc BackendCreateFunc) service.ModuleCreateFunc {
+	return NewNamedModule(DefaultBackendName, createFunc)
+}
+
+// NewNamedModule creates an async task queue module whose backend is registered under name,
+// so that a host with more than one task module (e.g. a default queue plus a low-priority
+// one) can look each up individually via GlobalBackendNamed.
+func NewNamedModule(name string, createFunc BackendCreateFunc) service.ModuleCreateFunc {
 	return func(mi service.ModuleCreateInfo) ([]service.Module, error) {
-		mod, err := newAsyncModuleSingleton(mi, createFunc)
+		mod, err := newAsyncModule(name, mi, createFunc)
 		return []service.Module{mod}, err
 	}
 }
 
-func newAsyncModuleSingleton(
-	mi service.ModuleCreateInfo, createFunc BackendCreateFunc,
-) (service.Module, error) {
-	_once.Do(func() {
-		_asyncMod, _asyncModErr = newAsyncModule(mi, createFunc)
-	})
-	return _asyncMod, _asyncModErr
-}
-
 func newAsyncModule(
-	mi service.ModuleCreateInfo, createFunc BackendCreateFunc,
+	name string, mi service.ModuleCreateInfo, createFunc BackendCreateFunc,
 ) (service.Module, error) {
 	SetupTaskMetrics(mi.Host.Metrics())
 	backend, err := createFunc(mi.Host)
 	if err != nil {
 		return nil, err
 	}
-	_globalBackendMu.Lock()
-	_globalBackend = backend
-	_globalBackendMu.Unlock()
+	RegisterBackend(name, backend)
 	return &AsyncModule{
 		Backend: backend,
 		modBase: *modules.NewModuleBase("task", mi.Host, []string{}),
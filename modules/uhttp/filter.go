@@ -0,0 +1,54 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package uhttp
+
+import "net/http"
+
+// Filter is a single step in the uhttp request-handling pipeline. It receives the next
+// Handler in the chain and should call it to continue processing, or short-circuit by
+// writing to w directly (e.g. to reject the request).
+type Filter interface {
+	Apply(w http.ResponseWriter, r *http.Request, next http.Handler)
+}
+
+// FilterFunc adapts an ordinary function to the Filter interface.
+type FilterFunc func(w http.ResponseWriter, r *http.Request, next http.Handler)
+
+// Apply implements Filter.
+func (f FilterFunc) Apply(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	f(w, r, next)
+}
+
+// newFilterChain wraps final with filters, applied in the order given, so that filters[0]
+// runs first and has the opportunity to short-circuit before filters[1], etc.
+func newFilterChain(filters []Filter, final http.Handler) http.Handler {
+	h := final
+	for i := len(filters) - 1; i >= 0; i-- {
+		h = chainLink(filters[i], h)
+	}
+	return h
+}
+
+func chainLink(f Filter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.Apply(w, r, next)
+	})
+}
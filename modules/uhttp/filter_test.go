@@ -0,0 +1,81 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package uhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFilterChainRunsInOrder(t *testing.T) {
+	var order []string
+	record := func(name string) Filter {
+		return FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+			order = append(order, name)
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "final")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	chain := newFilterChain([]Filter{record("first"), record("second")}, final)
+	w := httptest.NewRecorder()
+	chain.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"first", "second", "final"}, order)
+}
+
+func TestNewFilterChainShortCircuits(t *testing.T) {
+	reject := FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	neverRuns := FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		t.Fatal("neverRuns filter should not have run")
+	})
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("final handler should not have run")
+	})
+
+	chain := newFilterChain([]Filter{reject, neverRuns}, final)
+	w := httptest.NewRecorder()
+	chain.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestNewFilterChainEmpty(t *testing.T) {
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	chain := newFilterChain(nil, final)
+	w := httptest.NewRecorder()
+	chain.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
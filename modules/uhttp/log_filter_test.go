@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package uhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/fx/ulog"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogFilterSeedsRequestScopedFields(t *testing.T) {
+	log := ulog.Builder().WithConfiguration(&ulog.Configuration{}).Build()
+
+	var gotLog ulog.Log
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLog = ulog.Logger(r.Context())
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/widgets", nil)
+	r.Header.Set(_headerTraceID, "trace-123")
+	w := httptest.NewRecorder()
+	NewLogFilter(log).Apply(w, r, final)
+
+	require.NotNil(t, gotLog)
+	fields := gotLog.Fields()
+	assertField(t, fields, "method", http.MethodGet)
+	assertField(t, fields, "path", "/widgets")
+	assertField(t, fields, "traceID", "trace-123")
+
+	id, ok := fieldValue(fields, "requestID")
+	require.True(t, ok, "expected a requestID field")
+	assert.NotEmpty(t, id)
+}
+
+func TestNewLogFilterKeepsIncomingRequestID(t *testing.T) {
+	log := ulog.Builder().WithConfiguration(&ulog.Configuration{}).Build()
+
+	var gotLog ulog.Log
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLog = ulog.Logger(r.Context())
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(_headerRequestID, "req-abc")
+	w := httptest.NewRecorder()
+	NewLogFilter(log).Apply(w, r, final)
+
+	require.NotNil(t, gotLog)
+	assertField(t, gotLog.Fields(), "requestID", "req-abc")
+}
+
+func fieldValue(fields []ulog.Field, key string) (interface{}, bool) {
+	for _, f := range fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+func assertField(t *testing.T, fields []ulog.Field, key string, want interface{}) {
+	t.Helper()
+	got, ok := fieldValue(fields, key)
+	require.True(t, ok, "expected a %q field", key)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package observer provides recording test doubles for the interfaces modules use to do
+// work that's awkward to assert on directly: task.Backend, uhttp.Filter, and ulog.Log. Each
+// one is inspired by zap's zaptest/observer, capturing what happened in memory instead of
+// actually enqueuing a task, serving an HTTP request, or writing a log line, so a test can
+// assert against it afterward. All three are safe for concurrent use, including from
+// parallel t.Run subtests.
+package observer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/fx/modules/task"
+)
+
+// errNoTasks is returned by RunNext when there's nothing recorded left to run.
+var errNoTasks = fmt.Errorf("observer: RunNext called with no recorded tasks")
+
+// RecordedBackend is a task.Backend that captures every enqueued task in memory instead of
+// delivering it to a real broker. Register handlers with HandleFunc and drive them with
+// RunNext to exercise a module's task-handling code synchronously, without a running Redis
+// or AMQP instance.
+type RecordedBackend struct {
+	mu        sync.Mutex
+	tasks     []task.Task
+	handlers  map[string]func(ctx context.Context, payload map[string]interface{}) error
+	schedules map[string]task.ScheduleID
+}
+
+// NewRecordedBackend returns an empty RecordedBackend.
+func NewRecordedBackend() *RecordedBackend {
+	return &RecordedBackend{
+		handlers:  make(map[string]func(ctx context.Context, payload map[string]interface{}) error),
+		schedules: make(map[string]task.ScheduleID),
+	}
+}
+
+// HandleFunc registers h to process tasks named name when RunNext dequeues one. Mirrors the
+// HandleFunc method real backends (redis.Backend, amqp.Backend) expose.
+func (b *RecordedBackend) HandleFunc(name string, h func(ctx context.Context, payload map[string]interface{}) error) {
+	b.mu.Lock()
+	b.handlers[name] = h
+	b.mu.Unlock()
+}
+
+// Enqueue implements task.Backend by recording t, with ctx's logger fields stashed onto its
+// payload via task.EnqueueContext exactly as a real backend would, so a test driving RunNext
+// exercises the same worker-side ulog.Logger(ctx) propagation production code relies on.
+func (b *RecordedBackend) Enqueue(ctx context.Context, t task.Task) error {
+	t.Payload = task.EnqueueContext(ctx, t.Payload)
+	b.mu.Lock()
+	b.tasks = append(b.tasks, t)
+	b.mu.Unlock()
+	return nil
+}
+
+// EnqueueIn implements task.Backend by recording t; delay is ignored since nothing ever
+// delivers it.
+func (b *RecordedBackend) EnqueueIn(ctx context.Context, t task.Task, delay time.Duration) error {
+	return b.Enqueue(ctx, t)
+}
+
+// EnqueueAt implements task.Backend by recording t; fireTime is ignored since nothing ever
+// delivers it.
+func (b *RecordedBackend) EnqueueAt(ctx context.Context, t task.Task, fireTime time.Time) error {
+	return b.Enqueue(ctx, t)
+}
+
+// Schedule implements task.Backend by recording t once, immediately, rather than actually
+// recurring it on spec. Calling Schedule again with the same spec and t.Name is idempotent,
+// matching the real backends: it returns the already-registered ScheduleID without recording
+// a second task.
+func (b *RecordedBackend) Schedule(spec string, t task.Task) (task.ScheduleID, error) {
+	key := spec + "|" + t.Name
+
+	b.mu.Lock()
+	if id, ok := b.schedules[key]; ok {
+		b.mu.Unlock()
+		return id, nil
+	}
+	id := task.ScheduleID(key)
+	b.schedules[key] = id
+	b.mu.Unlock()
+
+	if err := b.Enqueue(context.Background(), t); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Start implements task.Backend as a no-op.
+func (b *RecordedBackend) Start() error { return nil }
+
+// Stop implements task.Backend as a no-op.
+func (b *RecordedBackend) Stop() error { return nil }
+
+// AllTasks returns every task recorded so far, in enqueue order.
+func (b *RecordedBackend) AllTasks() []task.Task {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]task.Task, len(b.tasks))
+	copy(out, b.tasks)
+	return out
+}
+
+// TasksByType returns the recorded tasks named name, in enqueue order.
+func (b *RecordedBackend) TasksByType(name string) []task.Task {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []task.Task
+	for _, t := range b.tasks {
+		if t.Name == name {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Drain returns every task recorded so far, in enqueue order, and clears them, so a test can
+// assert on one round of enqueues before moving on to the next.
+func (b *RecordedBackend) Drain() []task.Task {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := b.tasks
+	b.tasks = nil
+	return out
+}
+
+// RunNext pops the oldest recorded, not-yet-run task and invokes its registered handler
+// synchronously on the calling goroutine, returning errNoTasks if RunNext is called with
+// nothing recorded, an errUnhandled error if no handler is registered for the task's name, or
+// the handler's own error otherwise.
+func (b *RecordedBackend) RunNext() error {
+	b.mu.Lock()
+	if len(b.tasks) == 0 {
+		b.mu.Unlock()
+		return errNoTasks
+	}
+	t := b.tasks[0]
+	b.tasks = b.tasks[1:]
+	h, ok := b.handlers[t.Name]
+	b.mu.Unlock()
+
+	if !ok {
+		return errUnhandled(t.Name)
+	}
+	ctx := task.ContextWithLogFields(context.Background(), t.Payload)
+	return h(ctx, t.Payload)
+}
+
+func errUnhandled(name string) error {
+	return fmt.Errorf("observer: no handler registered for task %q", name)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"go.uber.org/fx/modules/uhttp"
+)
+
+// RecordedFilterChain runs a fixed list of uhttp.Filter against a synthetic request, recording
+// which of them actually ran so a test can assert on short-circuiting (e.g. an auth filter
+// rejecting a request before a logging filter after it ever runs).
+type RecordedFilterChain struct {
+	filters []uhttp.Filter
+
+	mu      sync.Mutex
+	invoked []int
+}
+
+// NewRecordedFilterChain returns a RecordedFilterChain that runs filters, in the order given,
+// ahead of whatever final handler Run is called with.
+func NewRecordedFilterChain(filters ...uhttp.Filter) *RecordedFilterChain {
+	return &RecordedFilterChain{filters: filters}
+}
+
+// Run sends r through the filter chain, ending in final, and returns an httptest.ResponseRecorder
+// capturing whatever the chain wrote. Call Invoked afterward for the indices, into the filters
+// passed to NewRecordedFilterChain, of the ones that actually ran.
+func (c *RecordedFilterChain) Run(r *http.Request, final http.Handler) *httptest.ResponseRecorder {
+	c.mu.Lock()
+	c.invoked = nil
+	c.mu.Unlock()
+
+	w := httptest.NewRecorder()
+	c.chain(0, final).ServeHTTP(w, r)
+	return w
+}
+
+// Invoked returns the 0-based indices, into the filters passed to NewRecordedFilterChain, of
+// the filters that ran during the most recent call to Run, in the order they ran.
+func (c *RecordedFilterChain) Invoked() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]int, len(c.invoked))
+	copy(out, c.invoked)
+	return out
+}
+
+func (c *RecordedFilterChain) chain(i int, final http.Handler) http.Handler {
+	if i >= len(c.filters) {
+		return final
+	}
+	next := c.chain(i+1, final)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		c.invoked = append(c.invoked, i)
+		c.mu.Unlock()
+		c.filters[i].Apply(w, r, next)
+	})
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observer
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+
+	"go.uber.org/fx/ulog"
+
+	"github.com/uber-go/zap"
+)
+
+// LoggedEntry is a single log call captured by a ContextRecorder.
+type LoggedEntry struct {
+	Level   zap.Level
+	Message string
+	Fields  []ulog.Field
+}
+
+// logSink is the state shared by a ContextRecorder and every Log derived from it via With, so
+// that asserting against the root recorder also sees entries logged through a With'd child.
+type logSink struct {
+	mu      sync.Mutex
+	entries []LoggedEntry
+}
+
+func (s *logSink) record(e LoggedEntry) {
+	s.mu.Lock()
+	s.entries = append(s.entries, e)
+	s.mu.Unlock()
+}
+
+func (s *logSink) all() []LoggedEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]LoggedEntry, len(s.entries))
+	copy(out, s.entries)
+	return out
+}
+
+// ContextRecorder is a ulog.Log, inspired by zap's zaptest/observer, that captures every log
+// call in memory instead of writing it anywhere, so a test can assert on the fields and level
+// a piece of code logged at without touching stdout. Install it on a request- or task-scoped
+// context with Context, the way a real caller would use ulog.NewLogContext, and inspect everything
+// logged through it (or a Log derived from it via With) with All.
+type ContextRecorder struct {
+	sink   *logSink
+	fields []ulog.Field
+
+	// checker is a real, discarding zap.Logger used only to give Check and SetLevel the same
+	// level-gating behavior a real ulog.Log has; fields written via a *zap.CheckedMessage it
+	// returns are not captured in All, since zap.CheckedMessage has no hook for observing them.
+	checker zap.Logger
+}
+
+// NewContextRecorder returns a ContextRecorder with no fields baked in yet.
+func NewContextRecorder() *ContextRecorder {
+	return &ContextRecorder{
+		sink:    &logSink{},
+		checker: zap.New(zap.NewJSONEncoder(), zap.Output(zap.AddSync(ioutil.Discard)), zap.DebugLevel),
+	}
+}
+
+// Context returns a copy of ctx carrying r, so that code under test recovers it via
+// ulog.Logger(ctx) exactly as it would a real Log installed by ulog.NewLogContext.
+func (r *ContextRecorder) Context(ctx context.Context) context.Context {
+	return ulog.NewLogContext(ctx, r)
+}
+
+// All returns every entry logged through r, or a Log derived from it via With, in call order.
+func (r *ContextRecorder) All() []LoggedEntry {
+	return r.sink.all()
+}
+
+func (r *ContextRecorder) record(lvl zap.Level, msg string, keyvals ...interface{}) {
+	fields := make([]ulog.Field, 0, len(r.fields)+len(keyvals)/2)
+	fields = append(fields, r.fields...)
+	fields = append(fields, keyvalsToFields(keyvals...)...)
+	r.sink.record(LoggedEntry{Level: lvl, Message: msg, Fields: fields})
+}
+
+// Debug implements ulog.Log by recording msg at zap.DebugLevel.
+func (r *ContextRecorder) Debug(msg string, keyvals ...interface{}) {
+	r.record(zap.DebugLevel, msg, keyvals...)
+}
+
+// Info implements ulog.Log by recording msg at zap.InfoLevel.
+func (r *ContextRecorder) Info(msg string, keyvals ...interface{}) {
+	r.record(zap.InfoLevel, msg, keyvals...)
+}
+
+// Warn implements ulog.Log by recording msg at zap.WarnLevel.
+func (r *ContextRecorder) Warn(msg string, keyvals ...interface{}) {
+	r.record(zap.WarnLevel, msg, keyvals...)
+}
+
+// Error implements ulog.Log by recording msg at zap.ErrorLevel.
+func (r *ContextRecorder) Error(msg string, keyvals ...interface{}) {
+	r.record(zap.ErrorLevel, msg, keyvals...)
+}
+
+// Panic implements ulog.Log by recording msg at zap.PanicLevel. Unlike a real Log, it does not
+// actually panic, so a test exercising a failure path doesn't crash the test binary.
+func (r *ContextRecorder) Panic(msg string, keyvals ...interface{}) {
+	r.record(zap.PanicLevel, msg, keyvals...)
+}
+
+// Fatal implements ulog.Log by recording msg at zap.FatalLevel. Unlike a real Log, it does not
+// actually exit the process, so a test exercising a failure path doesn't kill the test binary.
+func (r *ContextRecorder) Fatal(msg string, keyvals ...interface{}) {
+	r.record(zap.FatalLevel, msg, keyvals...)
+}
+
+// DFatal implements ulog.Log by recording msg at zap.ErrorLevel, DFatal's production-mode
+// severity; the recorder doesn't model ulog.Configuration.Development, so it can't reproduce
+// DFatal's development-mode escalation to a real panic.
+func (r *ContextRecorder) DFatal(msg string, keyvals ...interface{}) {
+	r.record(zap.ErrorLevel, msg, keyvals...)
+}
+
+// With implements ulog.Log by returning a ContextRecorder sharing r's sink, so entries logged
+// through the new Log still show up in r.All, plus r's fields with keyvals baked in after them.
+func (r *ContextRecorder) With(keyvals ...interface{}) ulog.Log {
+	newFields := keyvalsToFields(keyvals...)
+	fields := make([]ulog.Field, 0, len(r.fields)+len(newFields))
+	fields = append(fields, r.fields...)
+	fields = append(fields, newFields...)
+	return &ContextRecorder{sink: r.sink, fields: fields, checker: r.checker}
+}
+
+// Fields implements ulog.Log.
+func (r *ContextRecorder) Fields() []ulog.Field {
+	return r.fields
+}
+
+// SetLevel implements ulog.Log by changing the minimum level Check considers enabled.
+func (r *ContextRecorder) SetLevel(lvl zap.Level) {
+	r.checker.SetLevel(lvl)
+}
+
+// Check implements ulog.Log.
+func (r *ContextRecorder) Check(lvl zap.Level, msg string) *zap.CheckedMessage {
+	return r.checker.Check(lvl, msg)
+}
+
+// keyvalsToFields converts a flat key1, val1, key2, val2, ... slice, as accepted by every
+// ulog.Log method, into Fields. A trailing key without a value is dropped.
+func keyvalsToFields(keyvals ...interface{}) []ulog.Field {
+	fields := make([]ulog.Field, 0, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			continue
+		}
+		fields = append(fields, ulog.Field{Key: key, Value: keyvals[i+1]})
+	}
+	return fields
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/fx/modules/task"
+	"go.uber.org/fx/modules/uhttp"
+	"go.uber.org/fx/ulog"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordedBackendEnqueueAndRunNext(t *testing.T) {
+	b := NewRecordedBackend()
+
+	var gotCtx context.Context
+	var gotPayload map[string]interface{}
+	b.HandleFunc("greet", func(ctx context.Context, payload map[string]interface{}) error {
+		gotCtx = ctx
+		gotPayload = payload
+		return nil
+	})
+
+	require.NoError(t, b.Enqueue(context.Background(), task.Task{Name: "greet", Payload: map[string]interface{}{"who": "world"}}))
+	assert.Len(t, b.AllTasks(), 1)
+
+	require.NoError(t, b.RunNext())
+	assert.NotNil(t, gotCtx)
+	assert.Equal(t, "world", gotPayload["who"])
+}
+
+func TestRecordedBackendRunNextWithNoTasks(t *testing.T) {
+	b := NewRecordedBackend()
+	assert.Equal(t, errNoTasks, b.RunNext())
+}
+
+func TestRecordedBackendRunNextUnhandled(t *testing.T) {
+	b := NewRecordedBackend()
+	require.NoError(t, b.Enqueue(context.Background(), task.Task{Name: "unhandled"}))
+	assert.Error(t, b.RunNext())
+}
+
+func TestRecordedBackendDrain(t *testing.T) {
+	b := NewRecordedBackend()
+	require.NoError(t, b.Enqueue(context.Background(), task.Task{Name: "a"}))
+	require.NoError(t, b.Enqueue(context.Background(), task.Task{Name: "b"}))
+
+	drained := b.Drain()
+	assert.Len(t, drained, 2)
+	assert.Empty(t, b.AllTasks())
+}
+
+func TestRecordedBackendScheduleIsIdempotent(t *testing.T) {
+	b := NewRecordedBackend()
+	id1, err := b.Schedule("0 0 * * *", task.Task{Name: "daily"})
+	require.NoError(t, err)
+	id2, err := b.Schedule("0 0 * * *", task.Task{Name: "daily"})
+	require.NoError(t, err)
+
+	assert.Equal(t, id1, id2)
+	assert.Len(t, b.AllTasks(), 1)
+}
+
+func TestRecordedFilterChainRunsAllFiltersInOrder(t *testing.T) {
+	auth := uhttp.FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		next.ServeHTTP(w, r)
+	})
+	logging := uhttp.FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		next.ServeHTTP(w, r)
+	})
+
+	chain := NewRecordedFilterChain(auth, logging)
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	resp := chain.Run(httptest.NewRequest(http.MethodGet, "/", nil), final)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []int{0, 1}, chain.Invoked())
+}
+
+func TestRecordedFilterChainShortCircuits(t *testing.T) {
+	reject := uhttp.FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	neverRuns := uhttp.FilterFunc(func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		next.ServeHTTP(w, r)
+	})
+
+	chain := NewRecordedFilterChain(reject, neverRuns)
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	resp := chain.Run(httptest.NewRequest(http.MethodGet, "/", nil), final)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, []int{0}, chain.Invoked())
+}
+
+func TestContextRecorderCapturesEntries(t *testing.T) {
+	r := NewContextRecorder()
+	log := r.With("requestID", "abc")
+	log.Info("handled request", "status", 200)
+
+	entries := r.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "handled request", entries[0].Message)
+	assert.Contains(t, entries[0].Fields, ulog.Field{Key: "requestID", Value: "abc"})
+	assert.Contains(t, entries[0].Fields, ulog.Field{Key: "status", Value: 200})
+}
+
+func TestContextRecorderContextRoundTrips(t *testing.T) {
+	r := NewContextRecorder()
+	ctx := r.Context(context.Background())
+
+	ulog.Logger(ctx).Error("boom")
+
+	entries := r.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "boom", entries[0].Message)
+}
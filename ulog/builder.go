@@ -0,0 +1,164 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ulog
+
+import (
+	"os"
+	"time"
+
+	"go.uber.org/fx/ulog/sentry"
+
+	"github.com/uber-go/zap"
+)
+
+// SamplingConfig configures zap's log sampling: after Initial entries with the same
+// message/level in a one second window, only every Thereafter'th one is logged.
+type SamplingConfig struct {
+	Initial    int `yaml:"initial"`
+	Thereafter int `yaml:"thereafter"`
+}
+
+// Configuration defines the configuration for ulog, typically loaded from a service's YAML
+// config under the `logging` key.
+type Configuration struct {
+	Level       string                `yaml:"level"`
+	Stdout      bool                  `yaml:"stdout"`
+	Development bool                  `yaml:"development"`
+	Sampling    *SamplingConfig       `yaml:"sampling"`
+	File        *FileConfig           `yaml:"file"`
+	Sentry      *sentry.Configuration `yaml:"sentry"`
+}
+
+// LogBuilder builds a Log, pre-initialized with configuration, a custom zap.Logger, or a
+// minimum level, prior to Build() returning the usable Log.
+type LogBuilder struct {
+	config  *Configuration
+	logger  zap.Logger
+	hooks   []zap.Hook
+	sinks   []zap.WriteSyncer
+	rotator *Rotator
+
+	stopRotatorWatch func()
+}
+
+// Builder returns a new LogBuilder with the production preset applied.
+func Builder() *LogBuilder {
+	return &LogBuilder{}
+}
+
+// WithConfiguration injects a Configuration to use when Build is called.
+func (b *LogBuilder) WithConfiguration(cfg *Configuration) *LogBuilder {
+	b.config = cfg
+	return b
+}
+
+// WithLogger overrides the underlying zap.Logger entirely, bypassing Configuration.
+func (b *LogBuilder) WithLogger(logger zap.Logger) *LogBuilder {
+	b.logger = logger
+	return b
+}
+
+// WithSentryHook attaches a Sentry hook built via the sentry package, in addition to (or
+// instead of, if no DSN is configured) one derived from Configuration.Sentry.
+func (b *LogBuilder) WithSentryHook(hook *sentry.Hook) *LogBuilder {
+	b.hooks = append(b.hooks, hook)
+	return b
+}
+
+// WithSink adds an arbitrary destination (e.g. Kafka, syslog) to the logger being built, in
+// addition to the stdout/file sinks derived from Configuration. All configured sinks receive
+// every entry the logger lets through, combined via zap.MultiWriteSyncer.
+func (b *LogBuilder) WithSink(ws zap.WriteSyncer) *LogBuilder {
+	b.sinks = append(b.sinks, ws)
+	return b
+}
+
+// Build assembles the configured Log.
+func (b *LogBuilder) Build() Log {
+	if b.logger != nil {
+		return newBaseLogger(b.logger, nil)
+	}
+
+	cfg := b.config
+	if cfg == nil {
+		cfg = &Configuration{Stdout: true}
+	}
+
+	opts := []zap.Option{parseLevel(cfg.Level)}
+	if cfg.Development {
+		opts = append(opts, zap.Development(), zap.AddCaller())
+	}
+	if cfg.Sampling != nil {
+		opts = append(opts, zap.Sample(time.Second, cfg.Sampling.Initial, cfg.Sampling.Thereafter))
+	}
+
+	var syncers []zap.WriteSyncer
+	if cfg.Stdout {
+		syncers = append(syncers, zap.AddSync(os.Stdout))
+	}
+	if cfg.File != nil {
+		b.rotator = NewRotator(*cfg.File)
+		b.stopRotatorWatch = b.rotator.WatchSIGHUP()
+		syncers = append(syncers, b.rotator)
+	}
+	syncers = append(syncers, b.sinks...)
+	if len(syncers) > 0 {
+		opts = append(opts, zap.Output(zap.MultiWriteSyncer(syncers...)))
+	}
+
+	for _, h := range b.hooks {
+		opts = append(opts, zap.Hooks(h.CheckAndFire))
+	}
+	if cfg.Sentry != nil && cfg.Sentry.DSN != "" {
+		if hook, err := sentry.NewFromConfiguration(*cfg.Sentry); err == nil {
+			opts = append(opts, zap.Hooks(hook.CheckAndFire))
+		}
+	}
+
+	logger := zap.New(zap.NewJSONEncoder(), opts...)
+	return newBaseLogger(logger, nil)
+}
+
+// Close releases resources Build started on b's behalf, such as a configured File rotator's
+// SIGHUP watcher goroutine. It's a no-op if Build was never called or cfg.File was unset.
+// Callers that build a Log with a lifetime shorter than the process (e.g. LogModule.Stop,
+// or a test that calls Build repeatedly) must call this to avoid leaking that goroutine.
+func (b *LogBuilder) Close() {
+	if b.stopRotatorWatch != nil {
+		b.stopRotatorWatch()
+	}
+}
+
+// parseLevel turns the YAML-configured level name into a zap.Option, defaulting to Info for
+// an empty or unrecognized value.
+func parseLevel(level string) zap.Option {
+	lvl := zap.InfoLevel
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			lvl = zap.InfoLevel
+		}
+	}
+	return lvl
+}
+
+func defaultZapLogger() zap.Logger {
+	return zap.New(zap.NewJSONEncoder(), zap.Output(zap.AddSync(os.Stdout)))
+}
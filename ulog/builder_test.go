@@ -0,0 +1,95 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ulog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/zap"
+)
+
+func TestBuildDefaultsToInfoLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := Builder().
+		WithConfiguration(&Configuration{}).
+		WithSink(zap.AddSync(&buf)).
+		Build()
+
+	log.Debug("should be filtered")
+	log.Info("should appear")
+
+	assert.NotContains(t, buf.String(), "should be filtered")
+	assert.Contains(t, buf.String(), "should appear")
+}
+
+func TestBuildHonorsConfiguredLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := Builder().
+		WithConfiguration(&Configuration{Level: "error"}).
+		WithSink(zap.AddSync(&buf)).
+		Build()
+
+	log.Warn("should be filtered")
+	log.Error("should appear")
+
+	assert.NotContains(t, buf.String(), "should be filtered")
+	assert.Contains(t, buf.String(), "should appear")
+}
+
+func TestBuildWithCustomLoggerBypassesConfiguration(t *testing.T) {
+	custom := zap.New(zap.NewJSONEncoder(), zap.DebugLevel)
+	log := Builder().WithLogger(custom).Build()
+	require.NotNil(t, log)
+}
+
+func TestBuildAppliesSampling(t *testing.T) {
+	var buf bytes.Buffer
+	log := Builder().
+		WithConfiguration(&Configuration{
+			Sampling: &SamplingConfig{Initial: 1, Thereafter: 1000000},
+		}).
+		WithSink(zap.AddSync(&buf)).
+		Build()
+
+	for i := 0; i < 5; i++ {
+		log.Info("repeated message")
+	}
+
+	count := bytes.Count(buf.Bytes(), []byte("repeated message"))
+	assert.Less(t, count, 5, "sampling should have dropped at least one of 5 identical entries")
+}
+
+func TestBuildWritesToMultipleSinks(t *testing.T) {
+	var bufA, bufB bytes.Buffer
+	log := Builder().
+		WithConfiguration(&Configuration{}).
+		WithSink(zap.AddSync(&bufA)).
+		WithSink(zap.AddSync(&bufB)).
+		Build()
+
+	log.Info("fan out")
+
+	assert.Contains(t, bufA.String(), "fan out")
+	assert.Contains(t, bufB.String(), "fan out")
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ulog
+
+import (
+	"context"
+	"sync"
+)
+
+type logContextKey struct{}
+
+var (
+	_defaultLoggerMu sync.RWMutex
+	_defaultLogger   Log = newBaseLogger(defaultZapLogger(), nil)
+)
+
+// NewLogContext returns a copy of ctx carrying log, so that a later call to Logger(ctx) along
+// the same request/task chain recovers it.
+func NewLogContext(ctx context.Context, log Log) context.Context {
+	return context.WithValue(ctx, logContextKey{}, log)
+}
+
+// Logger returns the Log stored on ctx by NewLogContext, or the process-wide default logger
+// (as installed by Module, or the stdout logger if no fx service has configured one yet) if
+// ctx carries none.
+func Logger(ctx context.Context) Log {
+	if log, ok := ctx.Value(logContextKey{}).(Log); ok {
+		return log
+	}
+	return defaultLogger()
+}
+
+func defaultLogger() Log {
+	_defaultLoggerMu.RLock()
+	defer _defaultLoggerMu.RUnlock()
+	return _defaultLogger
+}
+
+// setDefaultLogger installs log as the process-wide fallback used by Logger when a context
+// carries none. It is called by Module once the service's Configuration has been loaded.
+func setDefaultLogger(log Log) {
+	_defaultLoggerMu.Lock()
+	_defaultLogger = log
+	_defaultLoggerMu.Unlock()
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ulog
+
+import (
+	"github.com/uber-go/zap"
+)
+
+// Field is a single logging key/value pair. Unlike zap.Field, it retains the raw Go value
+// rather than a type-erased internal representation, so it can be round-tripped through a
+// map[string]interface{} (e.g. to cross a task queue boundary) and later turned back into a
+// zap.Field for actual emission.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// keyvalsToRawFields converts a flat key1, val1, key2, val2, ... slice, as accepted by the
+// Log methods, into Fields. A trailing key without a value is dropped.
+func keyvalsToRawFields(keyvals ...interface{}) []Field {
+	fields := make([]Field, 0, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			continue
+		}
+		fields = append(fields, Field{Key: key, Value: keyvals[i+1]})
+	}
+	return fields
+}
+
+// keyvalsToFields converts a flat key1, val1, ... slice directly into zap.Fields, for the
+// common case of a single logging call (no need to retain them as Fields).
+func keyvalsToFields(keyvals ...interface{}) []zap.Field {
+	return fieldsToZap(keyvalsToRawFields(keyvals...))
+}
+
+// fieldsToZap converts Fields into the equivalent zap.Fields, picking the most specific zap
+// constructor available for the underlying Go type and falling back to zap.Object.
+func fieldsToZap(fields []Field) []zap.Field {
+	zfields := make([]zap.Field, len(fields))
+	for i, f := range fields {
+		zfields[i] = fieldToZap(f)
+	}
+	return zfields
+}
+
+func fieldToZap(f Field) zap.Field {
+	switch v := f.Value.(type) {
+	case string:
+		return zap.String(f.Key, v)
+	case bool:
+		return zap.Bool(f.Key, v)
+	case int:
+		return zap.Int(f.Key, v)
+	case int64:
+		return zap.Int64(f.Key, v)
+	case float64:
+		return zap.Float64(f.Key, v)
+	case error:
+		return zap.String(f.Key, v.Error())
+	default:
+		return zap.Object(f.Key, f.Value)
+	}
+}
+
+// FieldsToMap flattens Fields into a plain map, suitable for serialization (e.g. as part of
+// a task payload).
+func FieldsToMap(fields []Field) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		m[f.Key] = f.Value
+	}
+	return m
+}
+
+// MapToFields is the inverse of FieldsToMap. Key ordering is not preserved, since maps are
+// unordered; this only matters for log readability, not correctness.
+func MapToFields(m map[string]interface{}) []Field {
+	fields := make([]Field, 0, len(m))
+	for k, v := range m {
+		fields = append(fields, Field{Key: k, Value: v})
+	}
+	return fields
+}
+
+// FieldsToKeyvals flattens Fields back into the key1, val1, key2, val2, ... form accepted by
+// the Log methods and With.
+func FieldsToKeyvals(fields []Field) []interface{} {
+	keyvals := make([]interface{}, 0, len(fields)*2)
+	for _, f := range fields {
+		keyvals = append(keyvals, f.Key, f.Value)
+	}
+	return keyvals
+}
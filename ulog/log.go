@@ -0,0 +1,111 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ulog
+
+import (
+	"github.com/uber-go/zap"
+)
+
+// Log is the logging interface used throughout fx. It decouples callers from the concrete
+// logger backing it (currently zap) and accepts loosely-typed key/value pairs so services
+// don't need to import zap just to log a field.
+type Log interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+	Panic(msg string, keyvals ...interface{})
+	Fatal(msg string, keyvals ...interface{})
+	DFatal(msg string, keyvals ...interface{})
+
+	// With returns a new Log with keyvals baked in so they don't need to be repeated on
+	// every subsequent call.
+	With(keyvals ...interface{}) Log
+
+	// Fields returns the key/value pairs accumulated via With on this Log, in the order
+	// they were added. It is primarily used to propagate logger context across process
+	// boundaries, e.g. from an HTTP request into an enqueued task.
+	Fields() []Field
+
+	SetLevel(zap.Level)
+	Check(zap.Level, string) *zap.CheckedMessage
+}
+
+// baseLogger is the default Log implementation, backed by a zap.Logger.
+type baseLogger struct {
+	log    zap.Logger
+	fields []Field
+}
+
+// newBaseLogger wraps a zap.Logger as a Log, optionally carrying a set of already-baked
+// fields (used when reconstructing a logger from a propagated Fields slice).
+func newBaseLogger(log zap.Logger, fields []Field) *baseLogger {
+	return &baseLogger{log: log, fields: fields}
+}
+
+func (l *baseLogger) Debug(msg string, keyvals ...interface{}) {
+	l.log.Debug(msg, keyvalsToFields(keyvals...)...)
+}
+
+func (l *baseLogger) Info(msg string, keyvals ...interface{}) {
+	l.log.Info(msg, keyvalsToFields(keyvals...)...)
+}
+
+func (l *baseLogger) Warn(msg string, keyvals ...interface{}) {
+	l.log.Warn(msg, keyvalsToFields(keyvals...)...)
+}
+
+func (l *baseLogger) Error(msg string, keyvals ...interface{}) {
+	l.log.Error(msg, keyvalsToFields(keyvals...)...)
+}
+
+func (l *baseLogger) Panic(msg string, keyvals ...interface{}) {
+	l.log.Panic(msg, keyvalsToFields(keyvals...)...)
+}
+
+func (l *baseLogger) Fatal(msg string, keyvals ...interface{}) {
+	l.log.Fatal(msg, keyvalsToFields(keyvals...)...)
+}
+
+func (l *baseLogger) DFatal(msg string, keyvals ...interface{}) {
+	l.log.DFatal(msg, keyvalsToFields(keyvals...)...)
+}
+
+func (l *baseLogger) With(keyvals ...interface{}) Log {
+	newFields := keyvalsToRawFields(keyvals...)
+	fields := make([]Field, 0, len(l.fields)+len(newFields))
+	fields = append(fields, l.fields...)
+	fields = append(fields, newFields...)
+
+	return newBaseLogger(l.log.With(fieldsToZap(newFields)...), fields)
+}
+
+func (l *baseLogger) Fields() []Field {
+	return l.fields
+}
+
+func (l *baseLogger) SetLevel(lvl zap.Level) {
+	l.log.SetLevel(lvl)
+}
+
+func (l *baseLogger) Check(lvl zap.Level, msg string) *zap.CheckedMessage {
+	return l.log.Check(lvl, msg)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ulog
+
+import (
+	"go.uber.org/fx/modules"
+	"go.uber.org/fx/service"
+)
+
+const _configKey = "logging"
+
+// Module builds a Log from the host's `logging` configuration (see Configuration) and
+// installs it as the process-wide default, so that ulog.Logger(ctx) returns a correctly
+// configured logger even outside of a request/task context. Other modules, such as uhttp and
+// task, layer request- or task-scoped fields on top of it via NewLogContext.
+//
+// Usage:
+//
+//   svc, err := service.New(service.WithModules(ulog.Module))
+func Module(mi service.ModuleCreateInfo) ([]service.Module, error) {
+	mod, err := newLogModule(mi)
+	if err != nil {
+		return nil, err
+	}
+	return []service.Module{mod}, nil
+}
+
+func newLogModule(mi service.ModuleCreateInfo) (service.Module, error) {
+	var cfg Configuration
+	if err := mi.Host.Config().Get(_configKey).PopulateStruct(&cfg); err != nil {
+		return nil, err
+	}
+
+	builder := Builder().WithConfiguration(&cfg)
+	log := builder.Build()
+	setDefaultLogger(log)
+
+	return &LogModule{
+		Log:     log,
+		modBase: *modules.NewModuleBase("ulog", mi.Host, []string{}),
+		builder: builder,
+	}, nil
+}
+
+// LogModule is the fx module, created by Module, wrapping the configured Log.
+type LogModule struct {
+	Log
+	modBase modules.ModuleBase
+	builder *LogBuilder
+}
+
+// Stop releases resources the module's Log holds open, namely a configured File rotator's
+// SIGHUP watcher goroutine, so it doesn't outlive the module.
+func (m *LogModule) Stop() error {
+	m.builder.Close()
+	return nil
+}
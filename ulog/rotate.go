@@ -0,0 +1,243 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ulog
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileConfig configures rotating file output for ulog, in the style of lumberjack. Path may
+// contain a strftime-style pattern (e.g. "app.%Y%m%d.log") to additionally rotate on time
+// boundaries; a plain path only rotates on size.
+type FileConfig struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"maxSizeMB"`
+	MaxBackups int    `yaml:"maxBackups"`
+	MaxAgeDays int    `yaml:"maxAgeDays"`
+	Compress   bool   `yaml:"compress"`
+}
+
+// Rotator is a zap.WriteSyncer that writes to FileConfig.Path, rotating the underlying file
+// once it exceeds MaxSizeMB or the strftime-expanded name changes, and pruning backups past
+// MaxBackups/MaxAgeDays. It also reopens the file in place on SIGHUP (see WatchSIGHUP), so it
+// cooperates with an external logrotate that has already renamed the file out from under it.
+type Rotator struct {
+	cfg FileConfig
+
+	mu          sync.Mutex
+	file        *os.File
+	currentName string
+	size        int64
+}
+
+// NewRotator creates a Rotator for cfg. The first Write opens (or creates) the file.
+func NewRotator(cfg FileConfig) *Rotator {
+	return &Rotator{cfg: cfg}
+}
+
+// Write implements zap.WriteSyncer.
+func (r *Rotator) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := expandStrftime(r.cfg.Path, time.Now())
+	if r.file == nil || name != r.currentName {
+		if err := r.openLocked(name); err != nil {
+			return 0, err
+		}
+		// A strftime boundary change leaves the previous dated file on disk as-is (unlike
+		// size-triggered rotation, it's never renamed aside), so prune previously-dated
+		// files directly against MaxBackups/MaxAgeDays here too.
+		if err := pruneBackups(strftimeGlob(r.cfg.Path), r.cfg.MaxBackups, r.cfg.MaxAgeDays); err != nil {
+			return 0, err
+		}
+	} else if r.cfg.MaxSizeMB > 0 && r.size+int64(len(p)) > int64(r.cfg.MaxSizeMB)*1024*1024 {
+		if err := r.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Sync implements zap.WriteSyncer.
+func (r *Rotator) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Sync()
+}
+
+// Reopen closes and reopens the current log file without changing its name, so that a
+// logrotate process that has already renamed the file out from under us starts a fresh one.
+func (r *Rotator) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := r.currentName
+	if name == "" {
+		return nil
+	}
+	return r.openLocked(name)
+}
+
+// WatchSIGHUP starts a goroutine that calls Reopen on every SIGHUP, for logrotate
+// compatibility, and returns a func that stops it. Build wires this up automatically when
+// Configuration.File is set, and the LogBuilder's Close stops it; callers embedding a Rotator
+// directly via WithSink should call it themselves.
+func (r *Rotator) WatchSIGHUP() (stop func()) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sighup:
+				r.Reopen()
+			case <-done:
+				signal.Stop(sighup)
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (r *Rotator) rotateLocked() error {
+	if r.file != nil {
+		r.file.Close()
+	}
+	if err := r.rollBackups(); err != nil {
+		return err
+	}
+	return r.openLocked(r.currentName)
+}
+
+func (r *Rotator) openLocked(name string) error {
+	if r.file != nil {
+		r.file.Close()
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.currentName = name
+	r.size = info.Size()
+	return nil
+}
+
+// rollBackups renames the current file aside (appending a timestamp, and a .gz extension if
+// Compress is set) and prunes any backups past MaxBackups or MaxAgeDays. The backup's content
+// is left as it was written: with Compress set only its name ends in .gz, it is not
+// gzip-compressed.
+func (r *Rotator) rollBackups() error {
+	if r.currentName == "" {
+		return nil
+	}
+	backup := fmt.Sprintf("%s.%s", r.currentName, time.Now().Format("20060102T150405.000"))
+	if r.cfg.Compress {
+		backup += ".gz"
+	}
+	if err := os.Rename(r.currentName, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return pruneBackups(r.currentName+".*", r.cfg.MaxBackups, r.cfg.MaxAgeDays)
+}
+
+// pruneBackups removes files matching pattern past maxAgeDays, then past maxBackups (both
+// checks skipped if their threshold is <= 0), keeping the newest ones. filepath.Glob returns
+// matches in lexical order, which sorts oldest-to-newest both for rollBackups' timestamp
+// suffixes and for strftimeGlob's fixed-width date/time fields, so the trailing matches kept
+// by the maxBackups check are always the most recent.
+func pruneBackups(pattern string, maxBackups, maxAgeDays int) error {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	if maxAgeDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -maxAgeDays)
+		kept := matches[:0]
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(m)
+				continue
+			}
+			kept = append(kept, m)
+		}
+		matches = kept
+	}
+	if maxBackups > 0 && len(matches) > maxBackups {
+		for _, m := range matches[:len(matches)-maxBackups] {
+			os.Remove(m)
+		}
+	}
+	return nil
+}
+
+// expandStrftime expands a small subset of strftime verbs (%Y, %m, %d, %H, %M, %S) in path
+// against t. A path with no verbs is returned unchanged, so plain (non-time-rotated) paths
+// are unaffected.
+func expandStrftime(path string, t time.Time) string {
+	r := strings.NewReplacer(
+		"%Y", t.Format("2006"),
+		"%m", t.Format("01"),
+		"%d", t.Format("02"),
+		"%H", t.Format("15"),
+		"%M", t.Format("04"),
+		"%S", t.Format("05"),
+	)
+	return r.Replace(path)
+}
+
+// strftimeGlob returns a filepath.Glob pattern matching every name expandStrftime could ever
+// produce from path, by replacing each recognized verb with "*", so time-boundary rotation can
+// find and prune its own previously-dated files the same way rollBackups prunes renamed ones.
+// A path with no verbs expands to itself, matching only that one (non-time-rotated) file.
+func strftimeGlob(path string) string {
+	r := strings.NewReplacer(
+		"%Y", "*",
+		"%m", "*",
+		"%d", "*",
+		"%H", "*",
+		"%M", "*",
+		"%S", "*",
+	)
+	return r.Replace(path)
+}
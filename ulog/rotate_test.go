@@ -0,0 +1,130 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ulog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandStrftime(t *testing.T) {
+	at := time.Date(2026, 7, 30, 9, 5, 3, 0, time.UTC)
+
+	assert.Equal(t, "app.log", expandStrftime("app.log", at))
+	assert.Equal(t, "app.20260730.log", expandStrftime("app.%Y%m%d.log", at))
+	assert.Equal(t, "app.20260730-090503.log", expandStrftime("app.%Y%m%d-%H%M%S.log", at))
+}
+
+func TestStrftimeGlobMatchesExpandedNames(t *testing.T) {
+	at := time.Date(2026, 7, 30, 9, 5, 3, 0, time.UTC)
+	pattern := strftimeGlob("app.%Y%m%d.log")
+
+	ok, err := filepath.Match(pattern, expandStrftime("app.%Y%m%d.log", at))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.Equal(t, "app.log", strftimeGlob("app.log"))
+}
+
+func TestRotatorRotateLockedPrunesBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	r := NewRotator(FileConfig{Path: path, MaxSizeMB: 0, MaxBackups: 2})
+	// Force rotation on every write regardless of MaxSizeMB by rotating manually between
+	// writes instead of relying on a real size threshold, keeping the test independent of
+	// exact byte counts.
+	for i := 0; i < 4; i++ {
+		_, err := r.Write([]byte(fmt.Sprintf("entry %d\n", i)))
+		require.NoError(t, err)
+		require.NoError(t, r.rotateLocked())
+	}
+
+	matches, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(matches), 2, "rollBackups should prune past MaxBackups")
+}
+
+func TestRotatorPrunesDatedBackupsOnTimeBoundary(t *testing.T) {
+	dir := t.TempDir()
+	pattern := filepath.Join(dir, "app.%Y%m%d.log")
+
+	// Seed three already-rotated dated files directly, simulating three prior days' worth of
+	// time-boundary rotation, so we don't have to wait real days for the boundary to change.
+	now := time.Now()
+	for _, daysAgo := range []int{3, 2, 1} {
+		day := now.AddDate(0, 0, -daysAgo).Format("20060102")
+		name := filepath.Join(dir, fmt.Sprintf("app.%s.log", day))
+		require.NoError(t, os.WriteFile(name, []byte("old\n"), 0644))
+	}
+
+	r := NewRotator(FileConfig{Path: pattern, MaxBackups: 1})
+	_, err := r.Write([]byte("today\n"))
+	require.NoError(t, err)
+
+	matches, err := filepath.Glob(strftimeGlob(pattern))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(matches), 1, "time-boundary rotation should prune past MaxBackups")
+
+	// The file just opened for "today" must always survive pruning.
+	assert.FileExists(t, r.currentName)
+}
+
+func TestRotatorReopenKeepsName(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	r := NewRotator(FileConfig{Path: path})
+	_, err := r.Write([]byte("first\n"))
+	require.NoError(t, err)
+	name := r.currentName
+
+	require.NoError(t, r.Reopen())
+	assert.Equal(t, name, r.currentName)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "first")
+}
+
+func TestPruneBackupsByAge(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "app.log")
+
+	oldFile := base + ".old"
+	require.NoError(t, os.WriteFile(oldFile, []byte("x"), 0644))
+	old := time.Now().AddDate(0, 0, -10)
+	require.NoError(t, os.Chtimes(oldFile, old, old))
+
+	newFile := base + ".new"
+	require.NoError(t, os.WriteFile(newFile, []byte("x"), 0644))
+
+	require.NoError(t, pruneBackups(base+".*", 0, 1))
+
+	assert.NoFileExists(t, oldFile)
+	assert.FileExists(t, newFile)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package sentry provides a zap.Hook that forwards log entries at or above a minimum level to
+// Sentry (https://sentry.io). See ulog's package doc for the YAML configuration shape.
+package sentry
+
+import (
+	"github.com/getsentry/raven-go"
+	"github.com/uber-go/zap"
+)
+
+// Configuration configures the Sentry integration. It is typically populated from the
+// `logging.sentry` section of a service's YAML config.
+type Configuration struct {
+	DSN   string      `yaml:"dsn"`
+	Trace TraceConfig `yaml:"trace"`
+}
+
+// TraceConfig controls stacktrace capture for events sent to Sentry.
+type TraceConfig struct {
+	Disabled bool `yaml:"disabled"`
+}
+
+// Hook is a zap.Hook that reports log entries to Sentry.
+type Hook struct {
+	client   *raven.Client
+	minLevel zap.Level
+	trace    bool
+}
+
+// Option configures a Hook returned by New.
+type Option func(*Hook)
+
+// MinLevel sets the minimum level at which entries are forwarded to Sentry. The default is
+// zap.ErrorLevel.
+func MinLevel(lvl zap.Level) Option {
+	return func(h *Hook) { h.minLevel = lvl }
+}
+
+// DisableTraces turns off stacktrace capture, overriding Configuration.Trace.Disabled.
+func DisableTraces() Option {
+	return func(h *Hook) { h.trace = false }
+}
+
+// New builds a Hook reporting to the Sentry project identified by dsn.
+func New(dsn string, opts ...Option) (*Hook, error) {
+	client, err := raven.New(dsn)
+	if err != nil {
+		return nil, err
+	}
+	h := &Hook{client: client, minLevel: zap.ErrorLevel, trace: true}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
+}
+
+// NewFromConfiguration builds a Hook from a Configuration, as loaded from YAML.
+func NewFromConfiguration(cfg Configuration) (*Hook, error) {
+	opts := []Option{}
+	if cfg.Trace.Disabled {
+		opts = append(opts, DisableTraces())
+	}
+	return New(cfg.DSN, opts...)
+}
+
+// CheckAndFire implements zap.Hook: it reports entries at or above h.minLevel to Sentry,
+// sending the message and level; the entry's fields are not attached to the event.
+func (h *Hook) CheckAndFire(entry *zap.Entry, fields ...zap.Field) error {
+	if h == nil || h.client == nil || entry.Level < h.minLevel {
+		return nil
+	}
+
+	packet := raven.NewPacket(entry.Message)
+	packet.Level = ravenLevel(entry.Level)
+	if h.trace {
+		packet.Interfaces = append(packet.Interfaces, raven.NewStacktrace(2, 3, nil))
+	}
+
+	_, errCh := h.client.Capture(packet, nil)
+	return <-errCh
+}
+
+func ravenLevel(lvl zap.Level) raven.Severity {
+	switch lvl {
+	case zap.DebugLevel:
+		return raven.DEBUG
+	case zap.InfoLevel:
+		return raven.INFO
+	case zap.WarnLevel:
+		return raven.WARNING
+	case zap.ErrorLevel:
+		return raven.ERROR
+	default:
+		return raven.FATAL
+	}
+}